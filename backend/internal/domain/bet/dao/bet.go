@@ -18,6 +18,8 @@ type states interface {
 	Placed() state
 	Lost() state
 	Won() state
+	Void() state
+	Cancelled() state
 }
 
 func (t *state) Placed() state {
@@ -32,14 +34,37 @@ func (t *state) Won() state {
 	return state("won")
 }
 
+// Void bet is refunded because its topic is voided
+func (t *state) Void() state {
+	return state("void")
+}
+
+// Cancelled bet is withdrawn by its owner before closing, stake is refunded minus cancellation fee
+func (t *state) Cancelled() state {
+	return state("cancelled")
+}
+
+// Revision of a bet, the prediction replaced by its owner
+type Revision struct {
+	Prediction  string     `json:"prediction" bson:"prediction"`
+	Probability *float64   `json:"probability,omitempty" bson:"probability,omitempty"`
+	At          *time.Time `json:"at" bson:"at"` // when it was replaced
+}
+
 // Bet database object
 type Bet struct {
-	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	CreatedAt  *time.Time         `json:"created_at" bson:"created_at,omitempty"`
-	UpdatedAt  *time.Time         `json:"updated_at" bson:"updated_at,omitempty"`
-	TopicID    string             `json:"topic_id" bson:"topic_id,omitempty"`
-	Owner      string             `json:"owner" bson:"owner,omitempty"` // who made the bet (email)
-	Prediction string             `json:"prediction" bson:"prediction"` // whats his/her prediction
-	Reputation int                `json:"reputation" bson:"reputation"` // how many reputation at stake
-	State      state              `json:"state" bson:"state"`
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CreatedAt   *time.Time         `json:"created_at" bson:"created_at,omitempty"`
+	UpdatedAt   *time.Time         `json:"updated_at" bson:"updated_at,omitempty"`
+	TopicID     string             `json:"topic_id" bson:"topic_id,omitempty"`
+	Owner       string             `json:"owner" bson:"owner,omitempty"`                       // who made the bet (email)
+	Prediction  string             `json:"prediction" bson:"prediction"`                       // whats his/her prediction
+	Probability *float64           `json:"probability,omitempty" bson:"probability,omitempty"` // of yes, between 0 and 100, for probability topic
+	Reputation  int                `json:"reputation" bson:"reputation"`                       // how many reputation at stake
+	State       state              `json:"state" bson:"state"`
+	Escrow      int64              `json:"escrow" bson:"escrow"`                             // stake withdrawn from owner when placed
+	Payout      int64              `json:"payout" bson:"payout"`                             // reputation delta applied at settlement
+	Settlement  string             `json:"settlement,omitempty" bson:"settlement,omitempty"` // ID of settlement job which settled the bet
+	Fee         int64              `json:"fee,omitempty" bson:"fee,omitempty"`               // kept from escrow when cancelled
+	Revisions   []*Revision        `json:"revisions,omitempty" bson:"revisions,omitempty"`   // replaced predictions, oldest first
 }
@@ -0,0 +1,180 @@
+// Package service of bet, escrows stake on top of basic service, bet can be revised or cancelled while placed
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
+	"github.com/di-collective/ditebak/backend/internal/domain/ledger"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	typedRepo "github.com/di-collective/ditebak/backend/pkg/repo/typed"
+	typedService "github.com/di-collective/ditebak/backend/pkg/service/typed"
+)
+
+// Service of bet
+type Service struct {
+	typedService.Service[*dao.Bet, *dao.Bet]
+	tx     repo.Transactor
+	mut    repo.Mutator
+	ledger *ledger.Service
+	floor  int64
+	fee    int
+}
+
+// New bet service
+// @tx: creates bet and escrows its stake as a transaction
+// @rps: persistence repository of bet
+// @mut: revises bet atomically, the same repository as rps
+// @ledger: withdraws stake from owner
+// @floor: owner's reputation can't fall below it after escrow
+// @fee: percentage of escrow kept when a bet is cancelled
+func New(tx repo.Transactor, rps typedRepo.Repository[*dao.Bet, *dao.Bet], mut repo.Mutator, ledger *ledger.Service, floor int64, fee int) *Service {
+	return &Service{
+		Service: typedService.Basic(rps),
+		tx:      tx,
+		mut:     mut,
+		ledger:  ledger,
+		floor:   floor,
+		fee:     fee,
+	}
+}
+
+// Create a bet and escrow its stake
+// Verification:
+// 1. Stake must be more than 0
+// 2. Owner's reputation after escrow can't fall below the floor, enforced by ledger
+// 3. Owner has no other bet placed on the topic, enforced by unique index
+// Then:
+// create the bet and withdraw its stake in a single transaction,
+// escrowed stake is paid out by settlement
+func (svc *Service) Create(ctx context.Context, bet *dao.Bet) (*dao.Bet, error) {
+	if bet.Reputation < 1 {
+		return nil, exception.New(http.StatusBadRequest, "Reputation at stake must be more than 0")
+	}
+
+	bet.Escrow = int64(bet.Reputation)
+	err := svc.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := svc.Service.Create(ctx, bet); err != nil {
+			return err
+		}
+
+		return svc.ledger.Withdraw(ctx, svc.floor, &ledger.Entry{
+			User:   bet.Owner,
+			Delta:  -bet.Escrow,
+			Reason: ledger.Reasons.Stake(),
+			Topic:  bet.TopicID,
+			Bet:    bet.ID.Hex(),
+		})
+	})
+	if exc, ok := exception.IsException(err); ok && exc.Code() == http.StatusConflict {
+		return nil, exception.New(http.StatusConflict, "Owner already placed a bet on topic %s", bet.TopicID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return bet, nil
+}
+
+// Revise prediction of a bet, the replaced one is kept as revision
+// Verification:
+// 1. Bet must exists and still placed
+// 2. Prediction must be changed
+// Then:
+// append the revision and update the bet in a single mutation, stake is unchanged
+// bet settled or cancelled meanwhile is a conflict
+func (svc *Service) Revise(ctx context.Context, id, prediction string, probability *float64) (*dao.Bet, error) {
+	bet, err := svc.placed(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if bet.Prediction == prediction && sameProbability(bet.Probability, probability) {
+		return nil, exception.New(http.StatusBadRequest, "Prediction is unchanged")
+	}
+
+	now := time.Now()
+	res, err := svc.mut.Mutate(ctx, id, repo.Mutation{
+		If: map[string]interface{}{"state": string(dao.BetStates.Placed())},
+		Set: map[string]interface{}{
+			"prediction":  prediction,
+			"probability": probability,
+			"updated_at":  now,
+		},
+		Push: map[string]interface{}{"revisions": &dao.Revision{
+			Prediction:  bet.Prediction,
+			Probability: bet.Probability,
+			At:          &now,
+		}},
+	})
+	if err == repo.ErrUnmatched {
+		return nil, exception.New(http.StatusConflict, "Bet has been settled or cancelled while revising")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return typedRepo.Cast[*dao.Bet](res)
+}
+
+// Cancel a bet and refund its escrow minus cancellation fee
+// Verification:
+// 1. Bet must exists and still placed
+// Then:
+// flag the bet as cancelled and refund the owner in a single transaction, the fee is burned
+func (svc *Service) Cancel(ctx context.Context, id string) (*dao.Bet, error) {
+	bet := &dao.Bet{}
+	err := svc.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		placed, err := svc.placed(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		*bet = *placed
+		bet.State = dao.BetStates.Cancelled()
+		bet.Fee = bet.Escrow * int64(svc.fee) / 100
+		bet.Payout = bet.Escrow - bet.Fee
+		if _, err = svc.Service.Update(ctx, id, bet); err != nil {
+			return err
+		}
+
+		return svc.ledger.Post(ctx, &ledger.Entry{
+			User:   bet.Owner,
+			Delta:  bet.Payout,
+			Reason: ledger.Reasons.Refund(),
+			Topic:  bet.TopicID,
+			Bet:    id,
+			Note:   fmt.Sprintf("Cancelled, fee: %d", bet.Fee),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bet, nil
+}
+
+// placed bet by its ID, settled or cancelled bet can't be changed
+func (svc *Service) placed(ctx context.Context, id string) (*dao.Bet, error) {
+	bet, err := svc.Service.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if bet.State != dao.BetStates.Placed() {
+		return nil, exception.New(http.StatusConflict, "Bet is already %s and can't be changed", bet.State)
+	}
+
+	return bet, nil
+}
+
+// sameProbability of two optional probabilities
+func sameProbability(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
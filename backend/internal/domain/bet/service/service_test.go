@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/memrepo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/repotest"
+	typedRepo "github.com/di-collective/ditebak/backend/pkg/repo/typed"
+)
+
+// racing mutator runs meanwhile between the read and the mutation of a bet
+type racing struct {
+	repo.Mutator
+	meanwhile func(ctx context.Context, id string)
+}
+
+func (r *racing) Mutate(ctx context.Context, id string, m repo.Mutation) (interface{}, error) {
+	if r.meanwhile != nil {
+		r.meanwhile(ctx, id)
+	}
+
+	return r.Mutator.Mutate(ctx, id, m)
+}
+
+func TestRevise(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string // bet is moved into it meanwhile, empty is no race
+		wantCode int
+	}{
+		{"placed", "", 0},
+		{"cancelled meanwhile", "cancelled", http.StatusConflict},
+		{"won meanwhile", "won", http.StatusConflict},
+		{"lost meanwhile", "lost", http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			rps := memrepo.New(memrepo.NewStore(), "bets", nil, func() interface{} { return &dao.Bet{} }, &repotest.Delegate{})
+			placed := &dao.Bet{ID: primitive.NewObjectID(), Owner: "a@b.c", Prediction: "yes", Reputation: 10, State: dao.BetStates.Placed()}
+			if err := rps.Create(ctx, placed); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			mut := &racing{Mutator: rps}
+			if tt.state != "" {
+				mut.meanwhile = func(ctx context.Context, id string) {
+					if _, err := rps.Mutate(ctx, id, repo.Mutation{Set: map[string]interface{}{"state": tt.state}}); err != nil {
+						t.Fatalf("Mutate() error = %v", err)
+					}
+				}
+			}
+
+			svc := New(rps, typedRepo.Of[*dao.Bet, *dao.Bet](rps), mut, nil, 0, 0)
+			revised, err := svc.Revise(ctx, placed.ID.Hex(), "no", nil)
+
+			res, _ := rps.Get(ctx, placed.ID.Hex())
+			stored := res.(*dao.Bet)
+			if tt.wantCode != 0 {
+				exc, ok := exception.IsException(err)
+				if !ok || exc.Code() != tt.wantCode {
+					t.Fatalf("Revise() error = %v, want code %d", err, tt.wantCode)
+				}
+				if string(stored.State) != tt.state || stored.Prediction != "yes" || len(stored.Revisions) != 0 {
+					t.Errorf("stored = %s %s with %d revisions, want %s yes with 0 revisions", stored.State, stored.Prediction, len(stored.Revisions), tt.state)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Revise() error = %v", err)
+			}
+			if revised.Prediction != "no" || revised.State != dao.BetStates.Placed() {
+				t.Errorf("Revise() = %s %s, want no placed", revised.Prediction, revised.State)
+			}
+			if len(stored.Revisions) != 1 || stored.Revisions[0].Prediction != "yes" {
+				t.Errorf("stored revisions = %v, want the replaced yes", stored.Revisions)
+			}
+		})
+	}
+}
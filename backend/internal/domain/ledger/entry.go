@@ -0,0 +1,78 @@
+package ledger
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Entry reasons
+var (
+	enum            = reason("")
+	Reasons reasons = &enum
+)
+
+type reason string
+
+type reasons interface {
+	Opening() reason
+	Grant() reason
+	Stake() reason
+	Won() reason
+	Lost() reason
+	Refund() reason
+	Reversal() reason
+	Adjustment() reason
+}
+
+// Opening balance of reputation earned before the ledger exists
+func (t *reason) Opening() reason {
+	return reason("opening")
+}
+
+// Grant of starting reputation for new user
+func (t *reason) Grant() reason {
+	return reason("grant")
+}
+
+// Stake escrowed when placing a bet
+func (t *reason) Stake() reason {
+	return reason("stake")
+}
+
+func (t *reason) Won() reason {
+	return reason("won")
+}
+
+func (t *reason) Lost() reason {
+	return reason("lost")
+}
+
+// Refund of escrowed stake when a topic is voided
+func (t *reason) Refund() reason {
+	return reason("refund")
+}
+
+// Reversal of previous settlement, when a topic is corrected or voided
+func (t *reason) Reversal() reason {
+	return reason("reversal")
+}
+
+// Adjustment made manually by admin
+func (t *reason) Adjustment() reason {
+	return reason("adjustment")
+}
+
+// Entry of reputation ledger database object, never updated once created
+type Entry struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CreatedAt  *time.Time         `json:"created_at" bson:"created_at,omitempty"`
+	User       string             `json:"user" bson:"user"`
+	Delta      int64              `json:"delta" bson:"delta"`
+	Balance    int64              `json:"balance" bson:"balance"` // reputation of user after the entry
+	Reason     reason             `json:"reason" bson:"reason"`
+	Topic      string             `json:"topic,omitempty" bson:"topic,omitempty"`
+	Bet        string             `json:"bet,omitempty" bson:"bet,omitempty"`
+	Settlement string             `json:"settlement,omitempty" bson:"settlement,omitempty"` // ID of settlement job
+	Note       string             `json:"note,omitempty" bson:"note,omitempty"`
+}
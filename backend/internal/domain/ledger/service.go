@@ -0,0 +1,282 @@
+// Package ledger keeps append-only history of reputation,
+// every change of user reputation is posted as ledger entries
+package ledger
+
+import (
+	"context"
+	"net/http"
+
+	userDao "github.com/di-collective/ditebak/backend/internal/domain/user/dao"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	"github.com/di-collective/ditebak/backend/pkg/service/basic"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Users repository, reputation is mutated atomically
+type Users interface {
+	repo.Reader
+	repo.Mutator
+}
+
+// Reconciled reputation of a user
+type Reconciled struct {
+	User   string `json:"user"`
+	Before int64  `json:"before"` // reputation before reconciliation
+	Ledger int64  `json:"ledger"` // sum of ledger entries, reputation after reconciliation
+	Drift  int64  `json:"drift"`
+}
+
+// Service of ledger
+type Service struct {
+	*basic.Service
+	tx      repo.Transactor
+	entries repo.Repository
+	users   Users
+}
+
+// New ledger service
+// @tx: posts entries and reputation as a transaction
+// @entries: ledger entry repository
+// @users: user repository
+func New(tx repo.Transactor, entries repo.Repository, users Users) *Service {
+	return &Service{
+		Service: basic.New(entries),
+		tx:      tx,
+		entries: entries,
+		users:   users,
+	}
+}
+
+// Post entries and increment reputation of their users in a single transaction
+// entry without delta is skipped, balance of entry is the reputation after it
+func (svc *Service) Post(ctx context.Context, entries ...*Entry) error {
+	return svc.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		for _, entry := range entries {
+			if err := svc.post(ctx, entry, nil); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Withdraw reputation of a user, rejected if the balance would fall below the floor
+// balance is checked and decremented atomically, concurrent withdrawal can't overdraw
+func (svc *Service) Withdraw(ctx context.Context, floor int64, entry *Entry) error {
+	if entry.Delta >= 0 {
+		return exception.New(http.StatusBadRequest, "Withdrawal must be negative, got %d", entry.Delta)
+	}
+
+	return svc.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		err := svc.post(ctx, entry, map[string]interface{}{
+			"reputation": map[string]interface{}{"$gte": floor - entry.Delta},
+		})
+		if err == repo.ErrUnmatched {
+			return exception.New(http.StatusUnprocessableEntity, "Insufficient reputation to withdraw %d, balance must stay at least %d", -entry.Delta, floor)
+		}
+
+		return err
+	})
+}
+
+// post an entry, reputation is incremented only if the user matches cond
+// first entry of a user holding reputation earned before the ledger is preceded by opening entry of it
+// user is mutated first, so concurrent posting of the same user waits for it and sees the opening
+func (svc *Service) post(ctx context.Context, entry *Entry, cond map[string]interface{}) error {
+	if entry.Delta == 0 {
+		return nil
+	}
+
+	res, err := svc.users.Mutate(ctx, entry.User, repo.Mutation{
+		If:  cond,
+		Inc: map[string]int64{"reputation": entry.Delta},
+	})
+	if err == mongo.ErrNoDocuments {
+		return exception.New(http.StatusNotFound, "User with ID: %s, is not found", entry.User)
+	} else if err != nil {
+		return err
+	}
+
+	entry.Balance = res.(*userDao.User).Reputation
+	if _, err := svc.open(ctx, entry.User, entry.Balance-entry.Delta); err != nil {
+		return err
+	}
+
+	return svc.entries.Create(ctx, entry)
+}
+
+// open ledger of a user with reputation earned before it, unless the user already has any entry
+// nothing is opened of zero reputation, sum of no entry is zero already
+func (svc *Service) open(ctx context.Context, user string, reputation int64) (bool, error) {
+	if reputation == 0 {
+		return false, nil
+	}
+
+	total, _, err := svc.entries.Find(ctx, repo.FindOptions{
+		Page:   1,
+		Size:   1,
+		Params: map[string]interface{}{"user": user},
+	})
+	if err != nil || total > 0 {
+		return false, err
+	}
+
+	return true, svc.entries.Create(ctx, &Entry{
+		User:    user,
+		Delta:   reputation,
+		Balance: reputation,
+		Reason:  Reasons.Opening(),
+	})
+}
+
+// Open ledger of every user holding reputation without any entry, returns number of opened users
+// must run before any posting, e.g. at startup, so reputation earned before the ledger is never lost
+func (svc *Service) Open(ctx context.Context) (int, error) {
+	opened := 0
+	opt := repo.FindOptions{Page: 1, Size: 500, Params: map[string]interface{}{}}
+	for {
+		total, rows, err := svc.users.Find(ctx, opt)
+		if err != nil {
+			return opened, err
+		}
+
+		for _, row := range rows {
+			user := row.(*userDao.User)
+			ok, err := svc.open(ctx, user.ID.Hex(), user.Reputation)
+			if err != nil {
+				return opened, err
+			} else if ok {
+				opened++
+			}
+		}
+
+		if len(rows) <= 0 || int64(opt.Page*opt.Size) >= total {
+			return opened, nil
+		}
+		opt.Page++
+	}
+}
+
+// Create an entry from outside of domain, only grant and adjustment are allowed
+func (svc *Service) Create(ctx context.Context, obj interface{}) (interface{}, error) {
+	entry := obj.(*Entry)
+	if entry.Reason != Reasons.Grant() && entry.Reason != Reasons.Adjustment() {
+		return nil, exception.New(http.StatusBadRequest, "Only grant or adjustment can be posted, got %s", entry.Reason)
+	}
+	if entry.User == "" {
+		return nil, exception.New(http.StatusBadRequest, "User can't be empty")
+	}
+	if entry.Delta == 0 {
+		return nil, exception.New(http.StatusBadRequest, "Delta can't be zero")
+	}
+
+	if entry.Reason == Reasons.Grant() {
+		return svc.Grant(ctx, entry)
+	}
+
+	return entry, svc.Post(ctx, entry)
+}
+
+// Grant starting reputation to a user once, granting again returns the first grant instead
+// so a retried or repeated grant is never posted twice
+func (svc *Service) Grant(ctx context.Context, entry *Entry) (*Entry, error) {
+	var granted *Entry
+	err := svc.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		_, rows, err := svc.entries.Find(ctx, repo.FindOptions{
+			Page:   1,
+			Size:   1,
+			Params: map[string]interface{}{"user": entry.User, "reason": Reasons.Grant()},
+		})
+		if err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			granted = rows[0].(*Entry)
+			return nil
+		}
+
+		granted = entry
+		return svc.post(ctx, entry, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return granted, nil
+}
+
+// Reconcile reputation of a user against the ledger, ledger always wins
+// user without any entry gets opening entry of current reputation instead
+func (svc *Service) Reconcile(ctx context.Context, user string) (*Reconciled, error) {
+	rec := &Reconciled{User: user}
+	err := svc.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		res, err := svc.users.Get(ctx, user)
+		if err == mongo.ErrNoDocuments {
+			return exception.New(http.StatusNotFound, "User with ID: %s, is not found", user)
+		} else if err != nil {
+			return err
+		}
+
+		rec.Before = res.(*userDao.User).Reputation
+		total, sum, err := svc.sum(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		if total <= 0 {
+			rec.Ledger = rec.Before
+			_, err := svc.open(ctx, user, rec.Before)
+			return err
+		}
+
+		rec.Ledger = sum
+		rec.Drift = rec.Before - sum
+		if rec.Drift == 0 {
+			return nil
+		}
+
+		// set only if unchanged since it was read, concurrent post would be overwritten otherwise
+		_, err = svc.users.Mutate(ctx, user, repo.Mutation{
+			If:  map[string]interface{}{"reputation": rec.Before},
+			Set: map[string]interface{}{"reputation": sum},
+		})
+		if err == repo.ErrUnmatched {
+			return exception.New(http.StatusConflict, "Reputation of user %s changed during reconciliation, please retry", user)
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// sum all entries of a user
+func (svc *Service) sum(ctx context.Context, user string) (int64, int64, error) {
+	var sum int64
+	opt := repo.FindOptions{
+		Page:   1,
+		Size:   1000,
+		Params: map[string]interface{}{"user": user},
+	}
+
+	for {
+		total, rows, err := svc.entries.Find(ctx, opt)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		for _, row := range rows {
+			sum += row.(*Entry).Delta
+		}
+
+		if len(rows) <= 0 || int64(opt.Page*opt.Size) >= total {
+			return total, sum, nil
+		}
+		opt.Page++
+	}
+}
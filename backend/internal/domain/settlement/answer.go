@@ -0,0 +1,138 @@
+package settlement
+
+import (
+	"math"
+	"sort"
+
+	betDao "github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
+	topicDao "github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
+)
+
+// Answer a topic
+type Answer struct {
+	Topic      string   `json:"topic"`
+	Answer     string   `json:"answer"` // option ID of multiple-choice topic
+	Variations []string `json:"variations"`
+	Choice     bool     `json:"choice"` // resolved by option ID, set by settlement
+
+	// matching of free text topic, set by settlement
+	Matcher  string `json:"matcher,omitempty"`
+	MaxEdits int    `json:"max_edits,omitempty"`
+
+	// scoring of number, date or probability topic, set by settlement
+	Kind  string           `json:"kind,omitempty"`
+	Bands []*topicDao.Band `json:"bands,omitempty"`
+	Rule  string           `json:"rule,omitempty"`
+}
+
+// Command to settle a topic
+type Command struct {
+	Answer
+	Kind   string `json:"kind"`   // answer (default), correct, or void
+	Reason string `json:"reason"` // required to correct or void
+}
+
+// IsTrue check prediction against answer
+// option ID must match exactly, free text is matched against answer and its variations by the matcher of topic
+func (ans *Answer) IsTrue(prediction string) bool {
+	if ans.Choice {
+		return prediction == ans.Answer
+	}
+
+	matcher, ok := NewMatcher(ans.Matcher, ans.MaxEdits)
+	if !ok {
+		return false
+	}
+
+	for _, answer := range append([]string{ans.Answer}, ans.Variations...) {
+		if matcher.Match(prediction, answer) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Score a bet in percentage of its stake, -100 loses all of it
+// Scoring:
+// 1. Text is either right or wrong
+// 2. Number and date are rewarded by the tolerance band they fall in
+// 3. Probability is scored by its rule, see scoreProbability
+func (ans *Answer) Score(bet *betDao.Bet) int {
+	kind, _ := topicDao.PredictionKinds.Parse(ans.Kind)
+	if kind == topicDao.PredictionKinds.Probability() {
+		return ans.scoreProbability(bet.Probability)
+	}
+
+	if !kind.Scored() {
+		if ans.IsTrue(bet.Prediction) {
+			return 100
+		}
+		return -100
+	}
+
+	answer, err := kind.Value(ans.Answer)
+	if err != nil {
+		return -100
+	}
+	predicted, err := kind.Value(bet.Prediction)
+	if err != nil {
+		return -100
+	}
+
+	bands := make([]*topicDao.Band, len(ans.Bands))
+	copy(bands, ans.Bands)
+	sort.Slice(bands, func(i, j int) bool { return bands[i].Within < bands[j].Within })
+
+	// prediction right at the edge of a band is within it, e.g. 1.0 is within 0.1 of 1.1
+	distance := math.Abs(answer-predicted) - epsilon*math.Max(1, math.Abs(answer))
+	for _, band := range bands {
+		if distance <= band.Within {
+			return bounded(band.Reward)
+		}
+	}
+
+	return -100
+}
+
+// epsilon of relative error in distance, e.g. 1.1 - 1.0 is slightly above 0.1
+const epsilon = 1e-12
+
+// bounded score between -100 and 100, loss is capped at the stake
+func bounded(score int) int {
+	if score < -100 {
+		return -100
+	} else if score > 100 {
+		return 100
+	}
+
+	return score
+}
+
+// scoreProbability given to yes, both rules are scaled so that
+// certainty scores 100 when right, 50% scores 0, and loss is capped at the stake
+// - brier: 100 * (1 - 4 * (p - outcome)^2)
+// - log: 100 * (1 + log2(probability given to outcome)), 0 given to outcome is -Inf before the cap
+func (ans *Answer) scoreProbability(probability *float64) int {
+	outcome, ok := topicDao.ParseOutcome(ans.Answer)
+	if !ok || probability == nil || math.IsNaN(*probability) {
+		return -100
+	}
+
+	p := math.Min(math.Max(*probability/100, 0), 1)
+	var score float64
+	if rule, _ := topicDao.ScoringRules.Parse(ans.Rule); rule == topicDao.ScoringRules.Log() {
+		if !outcome {
+			p = 1 - p
+		}
+		score = 100 * (1 + math.Log2(p))
+	} else {
+		o := 0.0
+		if outcome {
+			o = 1
+		}
+		score = 100 * (1 - 4*(p-o)*(p-o))
+	}
+
+	return int(math.Round(math.Max(score, -100)))
+}
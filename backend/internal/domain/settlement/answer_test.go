@@ -0,0 +1,135 @@
+package settlement
+
+import (
+	"math"
+	"testing"
+
+	betDao "github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
+	topicDao "github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
+)
+
+func TestScoreBands(t *testing.T) {
+	bands := []*topicDao.Band{
+		{Within: 10, Reward: 25},
+		{Within: 0, Reward: 100},
+		{Within: 2.5, Reward: 50},
+		{Within: 50, Reward: -50},
+	}
+
+	tests := []struct {
+		name       string
+		kind       string
+		bands      []*topicDao.Band
+		answer     string
+		prediction string
+		want       int
+	}{
+		{"exact", "number", bands, "100", "100", 100},
+		{"within narrowest band", "number", bands, "100", "101", 50},
+		{"edge of band", "number", bands, "100", "97.5", 50},
+		{"just outside band", "number", bands, "100", "97.4", 25},
+		{"edge after float error", "number", []*topicDao.Band{{Within: 0.1, Reward: 100}}, "1.1", "1.0", 100},
+		{"just outside after float error", "number", []*topicDao.Band{{Within: 0.1, Reward: 100}}, "1.1", "0.9999", -100},
+		{"edge of widest band", "number", bands, "100", "150", -50},
+		{"outside every band", "number", bands, "100", "150.5", -100},
+		{"below answer", "number", bands, "-5", "-15", 25},
+		{"exact only by default", "number", topicDao.ExactBands(), "3.14", "3.14", 100},
+		{"not exact by default", "number", topicDao.ExactBands(), "3.14", "3.15", -100},
+		{"no bands", "number", nil, "1", "1", -100},
+		{"unparsable prediction", "number", bands, "100", "a hundred", -100},
+		{"NaN prediction", "number", bands, "100", "NaN", -100},
+		{"infinite prediction", "number", bands, "100", "Inf", -100},
+		{"NaN answer", "number", bands, "NaN", "NaN", -100},
+		{"reward above 100 is bounded", "number", []*topicDao.Band{{Within: 0, Reward: 500}}, "1", "1", 100},
+		{"reward below -100 is bounded", "number", []*topicDao.Band{{Within: 0, Reward: -500}}, "1", "1", -100},
+		{"same date", "date", bands, "2024-03-01", "2024-03-01", 100},
+		{"date in days", "date", bands, "2024-03-01", "2024-03-03", 50},
+		{"date across month", "date", bands, "2024-03-01", "2024-02-20", 25},
+		{"date with time", "date", bands, "2024-03-01", "2024-03-03T12:00:00Z", 50},
+		{"date just outside band", "date", bands, "2024-03-01", "2024-03-03T12:00:01Z", 25},
+		{"unparsable date", "date", bands, "2024-03-01", "March 1st", -100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ans := &Answer{Kind: tt.kind, Bands: tt.bands, Answer: tt.answer}
+			if got := ans.Score(&betDao.Bet{Prediction: tt.prediction}); got != tt.want {
+				t.Errorf("Score(%s) of %s = %d, want %d", tt.prediction, tt.answer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreText(t *testing.T) {
+	tests := []struct {
+		name       string
+		ans        Answer
+		prediction string
+		want       int
+	}{
+		{"option", Answer{Choice: true, Answer: "a"}, "a", 100},
+		{"other option", Answer{Choice: true, Answer: "a"}, "b", -100},
+		{"variation", Answer{Answer: "jakarta", Variations: []string{"batavia"}}, "batavia", 100},
+		{"wrong text", Answer{Answer: "jakarta"}, "bandung", -100},
+		{"unknown matcher", Answer{Answer: "jakarta", Matcher: "nope"}, "jakarta", -100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ans.Score(&betDao.Bet{Prediction: tt.prediction}); got != tt.want {
+				t.Errorf("Score(%s) = %d, want %d", tt.prediction, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreProbability(t *testing.T) {
+	tests := []struct {
+		name        string
+		rule        string
+		answer      string
+		probability float64
+		want        int
+	}{
+		{"brier certain and right", "brier", "yes", 100, 100},
+		{"brier certain and wrong", "brier", "yes", 0, -100},
+		{"brier undecided", "brier", "yes", 50, 0},
+		{"brier leaning right", "brier", "yes", 75, 75},
+		{"brier leaning wrong", "brier", "yes", 40, -44},
+		{"brier wrong below the cap", "brier", "yes", 25, -100},
+		{"brier no answered", "brier", "no", 10, 96},
+		{"brier is default", "", "no", 30, 64},
+		{"log certain and right", "log", "yes", 100, 100},
+		{"log certain and wrong", "log", "yes", 0, -100},
+		{"log 0 given to no", "log", "no", 100, -100},
+		{"log 100 given to no", "log", "no", 0, 100},
+		{"log undecided", "log", "yes", 50, 0},
+		{"log quarter", "log", "yes", 25, -100},
+		{"log leaning right", "log", "yes", 80, 68},
+		{"log nearly wrong", "log", "yes", 0.001, -100},
+		{"above 100 is certainty", "brier", "yes", 150, 100},
+		{"below 0 is certainty", "log", "no", -5, 100},
+		{"NaN", "log", "yes", math.NaN(), -100},
+		{"NaN brier", "brier", "yes", math.NaN(), -100},
+		{"unknown outcome", "brier", "maybe", 50, -100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probability := tt.probability
+			ans := &Answer{Kind: "probability", Rule: tt.rule, Answer: tt.answer}
+			got := ans.Score(&betDao.Bet{Probability: &probability})
+			if got != tt.want {
+				t.Errorf("Score(%v) answered %s = %d, want %d", tt.probability, tt.answer, got, tt.want)
+			}
+			if got < -100 || got > 100 {
+				t.Errorf("Score(%v) = %d, out of -100 to 100", tt.probability, got)
+			}
+		})
+	}
+
+	ans := &Answer{Kind: "probability", Answer: "yes"}
+	if got := ans.Score(&betDao.Bet{}); got != -100 {
+		t.Errorf("Score() without probability = %d, want -100", got)
+	}
+}
@@ -0,0 +1,211 @@
+package settlement
+
+import (
+	"math/big"
+	"time"
+
+	betDao "github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
+	topicDao "github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Job states
+var (
+	enum             = state("")
+	JobStates states = &enum
+)
+
+type state string
+
+type states interface {
+	Pending() state
+	Running() state
+	Done() state
+	Failed() state
+}
+
+func (t *state) Pending() state {
+	return state("pending")
+}
+
+func (t *state) Running() state {
+	return state("running")
+}
+
+func (t *state) Done() state {
+	return state("done")
+}
+
+// Failed job is finished but has dead letters, or stopped by an error. It can be retried
+func (t *state) Failed() state {
+	return state("failed")
+}
+
+// Job kinds
+var (
+	kindEnum       = kind("")
+	JobKinds kinds = &kindEnum
+)
+
+type kind string
+
+type kinds interface {
+	Answer() kind
+	Correct() kind
+	Void() kind
+	Parse(string) (kind, bool)
+}
+
+// Answer settles bets of a closed topic
+func (t *kind) Answer() kind {
+	return kind("answer")
+}
+
+// Correct reverts previous settlement and settles bets again with the new answer
+func (t *kind) Correct() kind {
+	return kind("correct")
+}
+
+// Void reverts previous settlement, if any, and refunds all bets
+func (t *kind) Void() kind {
+	return kind("void")
+}
+
+// Parse string into known kind, empty string is answer
+func (t *kind) Parse(s string) (kind, bool) {
+	switch k := kind(s); k {
+	case "":
+		return t.Answer(), true
+	case t.Answer(), t.Correct(), t.Void():
+		return k, true
+	}
+
+	return "", false
+}
+
+// DeadLetter is a bet which failed to be settled after all attempts
+type DeadLetter struct {
+	Bet      string     `json:"bet" bson:"bet"`
+	Error    string     `json:"error" bson:"error"`
+	Attempts int        `json:"attempts" bson:"attempts"`
+	At       *time.Time `json:"at" bson:"at"`
+}
+
+// Pool of parimutuel settlement, losers' pool is shared among winners proportionally to their stake
+type Pool struct {
+	Burn    int   `json:"burn" bson:"burn"`       // percentage of losers' pool taken by house
+	Winners int64 `json:"winners" bson:"winners"` // total stake of winners
+	Losers  int64 `json:"losers" bson:"losers"`   // total stake of losers
+}
+
+// share of losers' pool for a winning stake, rounded down, the remainder is burned
+// burn out of 0 to 100 is clamped, nothing is shared without winners or losers
+func (p *Pool) share(stake int64) int64 {
+	if p.Winners <= 0 || p.Losers <= 0 || stake <= 0 {
+		return 0
+	}
+
+	burn := int64(p.Burn)
+	if burn < 0 {
+		burn = 0
+	} else if burn > 100 {
+		burn = 100
+	}
+
+	// stake times losers' pool overflows int64 on large pools
+	n := new(big.Int).Mul(big.NewInt(stake), big.NewInt(p.Losers))
+	n.Mul(n, big.NewInt(100-burn))
+	n.Quo(n, new(big.Int).Mul(big.NewInt(100), big.NewInt(p.Winners)))
+	return n.Int64()
+}
+
+// EarlyBird bonus of a topic, prediction placed earlier gains bigger bonus on its reward
+type EarlyBird struct {
+	Bonus   int        `json:"bonus" bson:"bonus"`     // percentage of prediction placed at opening
+	Opening *time.Time `json:"opening" bson:"opening"` // creation of topic
+	Closing *time.Time `json:"closing" bson:"closing"`
+}
+
+// Payout of a bet settled by a job
+type Payout struct {
+	Bet   string `json:"bet" bson:"bet"`
+	User  string `json:"user" bson:"user"`
+	State string `json:"state" bson:"state"`
+	Stake int64  `json:"stake" bson:"stake"`
+	Net   int64  `json:"net" bson:"net"` // reputation gained or lost by the bet
+}
+
+// Job of settlement database object
+type Job struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CreatedAt  *time.Time         `json:"created_at" bson:"created_at,omitempty"`
+	UpdatedAt  *time.Time         `json:"updated_at" bson:"updated_at,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at" bson:"finished_at,omitempty"`
+	By         string             `json:"by" bson:"by"` // who answered the topic (email)
+	Topic      string             `json:"topic" bson:"topic"`
+	Kind       kind               `json:"kind" bson:"kind"`
+	Answer     *Answer            `json:"answer" bson:"answer"`                         // nil when voided
+	Previous   string             `json:"previous,omitempty" bson:"previous,omitempty"` // answer replaced by correction
+	Reason     string             `json:"reason,omitempty" bson:"reason,omitempty"`     // why topic is corrected or voided
+	State      state              `json:"state" bson:"state"`
+	Error      string             `json:"error,omitempty" bson:"error,omitempty"` // why job stopped
+	Processed  int                `json:"processed" bson:"processed"`             // bets settled by this job
+	Pool       *Pool              `json:"pool,omitempty" bson:"pool,omitempty"`   // nil unless parimutuel
+	Difficulty int                `json:"difficulty" bson:"difficulty"`           // percentage of bettors who got it wrong
+	Weighted   bool               `json:"weighted" bson:"weighted"`               // reward is scaled by difficulty
+	EarlyBird  *EarlyBird         `json:"early_bird,omitempty" bson:"early_bird,omitempty"`
+	Stat       *Settled           `json:"stat" bson:"stat"`       // final stat once done
+	Payouts    []*Payout          `json:"payouts" bson:"payouts"` // final payout per user once done
+
+	DeadLetters []*DeadLetter `json:"dead_letters" bson:"dead_letters"`
+	Runner      string        `json:"runner,omitempty" bson:"runner,omitempty"` // replica running the job, claimed by another once stale
+}
+
+// reward of a winning stake placed at the time, the stake itself unless parimutuel, weighted or early bird
+// weighted reward gains the difficulty as bonus percentage, e.g. 80% difficulty pays 1.8x
+// early bird bonus is applied on top of it
+func (job *Job) reward(stake int64, at *time.Time) int64 {
+	if job.Pool != nil {
+		return job.Pool.share(stake)
+	}
+	if job.Weighted {
+		stake = stake * int64(100+job.Difficulty) / 100
+	}
+	if eb := job.EarlyBird; eb != nil {
+		stake = stake * int64(100+topicDao.EarlyBonus(eb.Bonus, eb.Opening, eb.Closing, at)) / 100
+	}
+
+	return stake
+}
+
+// apply outcome of the job to a bet, escrowed stake is released on top of the outcome
+// prediction scored above 0 wins, otherwise it loses some or all of its stake, only refund if voided
+func (job *Job) apply(bet *betDao.Bet) {
+	if job.Kind == JobKinds.Void() {
+		bet.State = betDao.BetStates.Void()
+		bet.Payout = bet.Escrow
+		return
+	}
+
+	score := int64(job.Answer.Score(bet))
+	stake := int64(bet.Reputation)
+	if score > 0 {
+		bet.State = betDao.BetStates.Won()
+		bet.Payout = bet.Escrow + job.reward(stake*score/100, bet.CreatedAt)
+	} else {
+		bet.State = betDao.BetStates.Lost()
+		bet.Payout = bet.Escrow + stake*score/100
+	}
+}
+
+// deadBets ID to exclude from next round of settlement
+func (job *Job) deadBets() []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(job.DeadLetters))
+	for _, dl := range job.DeadLetters {
+		if id, err := primitive.ObjectIDFromHex(dl.Bet); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,241 @@
+package settlement
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	betDao "github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
+	topicDao "github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
+)
+
+func TestPoolShare(t *testing.T) {
+	tests := []struct {
+		name  string
+		pool  Pool
+		stake int64
+		want  int64
+	}{
+		{"proportional", Pool{Winners: 100, Losers: 300}, 40, 120},
+		{"whole pool", Pool{Winners: 100, Losers: 300}, 100, 300},
+		{"burn", Pool{Burn: 10, Winners: 100, Losers: 300}, 40, 108},
+		{"burn all", Pool{Burn: 100, Winners: 100, Losers: 300}, 40, 0},
+		{"rounded down", Pool{Winners: 3, Losers: 10}, 1, 3},
+		{"rounded down after burn", Pool{Burn: 5, Winners: 3, Losers: 10}, 1, 3},
+		{"less than one", Pool{Winners: 1000, Losers: 1}, 1, 0},
+		{"negative burn", Pool{Burn: -10, Winners: 100, Losers: 300}, 40, 120},
+		{"burn above 100", Pool{Burn: 150, Winners: 100, Losers: 300}, 40, 0},
+		{"no winners", Pool{Winners: 0, Losers: 300}, 40, 0},
+		{"no losers", Pool{Winners: 100, Losers: 0}, 40, 0},
+		{"no stake", Pool{Winners: 100, Losers: 300}, 0, 0},
+		{"large pool", Pool{Winners: math.MaxInt64 / 2, Losers: math.MaxInt64 / 2}, math.MaxInt64 / 4, math.MaxInt64 / 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pool.share(tt.stake); got != tt.want {
+				t.Errorf("share(%d) = %d, want %d", tt.stake, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSharesNeverExceedPool(t *testing.T) {
+	stakes := []int64{7, 13, 29, 51}
+	pool := Pool{Burn: 3, Losers: 997}
+	for _, stake := range stakes {
+		pool.Winners += stake
+	}
+
+	var shared int64
+	for _, stake := range stakes {
+		shared += pool.share(stake)
+	}
+
+	if max := pool.Losers * int64(100-pool.Burn) / 100; shared > max || shared < max-int64(len(stakes)) {
+		t.Errorf("shared = %d, want at most %d and lost at most 1 per winner by rounding", shared, max)
+	}
+}
+
+func TestApplyParimutuel(t *testing.T) {
+	tests := []struct {
+		name       string
+		pool       Pool
+		prediction string
+		stake      int
+		wantState  string
+		wantPayout int64
+	}{
+		{"winner", Pool{Burn: 10, Winners: 30, Losers: 70}, "a", 10, "won", 10 + 21},
+		{"loser", Pool{Burn: 10, Winners: 30, Losers: 70}, "b", 20, "lost", 0},
+		{"loser of zero-winner pool", Pool{Winners: 0, Losers: 70}, "b", 70, "lost", 0},
+		{"winner of zero-loser pool", Pool{Winners: 30, Losers: 0}, "a", 30, "won", 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := tt.pool
+			job := &Job{Kind: JobKinds.Answer(), Answer: &Answer{Choice: true, Answer: "a"}, Pool: &pool}
+			bet := &betDao.Bet{Prediction: tt.prediction, Reputation: tt.stake, Escrow: int64(tt.stake)}
+
+			job.apply(bet)
+			if string(bet.State) != tt.wantState || bet.Payout != tt.wantPayout {
+				t.Errorf("apply() = %s paying %d, want %s paying %d", bet.State, bet.Payout, tt.wantState, tt.wantPayout)
+			}
+		})
+	}
+}
+
+func TestDifficulty(t *testing.T) {
+	tests := []struct {
+		won, lost int
+		want      int
+	}{
+		{won: 0, lost: 0, want: 0},
+		{won: 10, lost: 0, want: 0},
+		{won: 0, lost: 10, want: 100},
+		{won: 1, lost: 1, want: 50},
+		{won: 1, lost: 2, want: 66},
+		{won: 2, lost: 1, want: 33},
+		{won: 1, lost: 999, want: 99},
+	}
+
+	for _, tt := range tests {
+		if got := difficulty(tt.won, tt.lost); got != tt.want {
+			t.Errorf("difficulty(%d, %d) = %d, want %d", tt.won, tt.lost, got, tt.want)
+		}
+	}
+}
+
+func TestRewardWeighted(t *testing.T) {
+	tests := []struct {
+		name       string
+		weighted   bool
+		difficulty int
+		stake      int64
+		want       int64
+	}{
+		{"flat", false, 80, 100, 100},
+		{"trivial", true, 0, 100, 100},
+		{"even", true, 50, 100, 150},
+		{"contrarian", true, 80, 100, 180},
+		{"nobody else right", true, 100, 100, 200},
+		{"rounded down", true, 33, 10, 13},
+		{"no stake", true, 80, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Weighted: tt.weighted, Difficulty: tt.difficulty}
+			if got := job.reward(tt.stake, nil); got != tt.want {
+				t.Errorf("reward(%d) = %d, want %d", tt.stake, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyWeighted(t *testing.T) {
+	job := &Job{
+		Kind:       JobKinds.Answer(),
+		Answer:     &Answer{Kind: "number", Answer: "100", Bands: []*topicDao.Band{{Within: 0, Reward: 100}, {Within: 5, Reward: 50}}},
+		Weighted:   true,
+		Difficulty: 60,
+	}
+
+	tests := []struct {
+		name       string
+		prediction string
+		want       int64
+	}{
+		{"exact", "100", 20 + 32},
+		{"close", "104", 20 + 16},
+		{"far", "200", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bet := &betDao.Bet{Prediction: tt.prediction, Reputation: 20, Escrow: 20}
+			if job.apply(bet); bet.Payout != tt.want {
+				t.Errorf("apply(%s) pays %d, want %d", tt.prediction, bet.Payout, tt.want)
+			}
+		})
+	}
+}
+
+func TestEarlyBonus(t *testing.T) {
+	opening := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	closing := opening.Add(100 * time.Hour)
+	at := func(d time.Duration) *time.Time {
+		t := opening.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name    string
+		max     int
+		opening *time.Time
+		closing *time.Time
+		at      *time.Time
+		want    int
+	}{
+		{"at opening", 50, &opening, &closing, at(0), 50},
+		{"before opening", 50, &opening, &closing, at(-time.Hour), 50},
+		{"quarter way", 50, &opening, &closing, at(25 * time.Hour), 37},
+		{"half way", 50, &opening, &closing, at(50 * time.Hour), 25},
+		{"just before closing", 50, &opening, &closing, at(99 * time.Hour), 0},
+		{"at closing", 50, &opening, &closing, &closing, 0},
+		{"after closing", 50, &opening, &closing, at(101 * time.Hour), 0},
+		{"no bonus", 0, &opening, &closing, at(0), 0},
+		{"negative bonus", -50, &opening, &closing, at(0), 0},
+		{"no opening", 50, nil, &closing, at(0), 0},
+		{"no closing", 50, &opening, nil, at(0), 0},
+		{"no time", 50, &opening, &closing, nil, 0},
+		{"closing before opening", 50, &closing, &opening, at(50 * time.Hour), 0},
+		{"open for decades", 100, &opening, at(30 * 365 * 24 * time.Hour), at(15 * 365 * 24 * time.Hour), 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := topicDao.EarlyBonus(tt.max, tt.opening, tt.closing, tt.at)
+			if got != tt.want {
+				t.Errorf("EarlyBonus() = %d, want %d", got, tt.want)
+			}
+			if got < 0 || (tt.max > 0 && got > tt.max) {
+				t.Errorf("EarlyBonus() = %d, out of 0 to %d", got, tt.max)
+			}
+		})
+	}
+}
+
+func TestRewardEarlyBird(t *testing.T) {
+	opening := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	closing := opening.Add(100 * time.Hour)
+	half := opening.Add(50 * time.Hour)
+
+	tests := []struct {
+		name     string
+		weighted bool
+		at       *time.Time
+		stake    int64
+		want     int64
+	}{
+		{"at opening", false, &opening, 100, 140},
+		{"half way", false, &half, 100, 120},
+		{"at closing", false, &closing, 100, 100},
+		{"unknown time", false, nil, 100, 100},
+		{"on top of weighting", true, &opening, 100, 150 * 140 / 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{
+				Weighted:   tt.weighted,
+				Difficulty: 50,
+				EarlyBird:  &EarlyBird{Bonus: 40, Opening: &opening, Closing: &closing},
+			}
+			if got := job.reward(tt.stake, tt.at); got != tt.want {
+				t.Errorf("reward(%d) = %d, want %d", tt.stake, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package settlement
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Matcher of free text prediction against an answer or one of its variations
+type Matcher interface {
+	Match(prediction, answer string) bool
+}
+
+// Validator of answer, implemented by matcher which can't accept any answer, e.g. regex
+type Validator interface {
+	Validate(answer string) error
+}
+
+// MatcherFactory builds a matcher from settings of a topic
+// @maxEdits: tolerated edit distance, only used by edit matcher
+type MatcherFactory func(maxEdits int) Matcher
+
+// matchers by name, selectable per topic
+var matchers = map[string]MatcherFactory{
+	"exact":      func(int) Matcher { return exact{} },
+	"normalized": func(int) Matcher { return normalized{} },
+	"numeric":    func(int) Matcher { return numeric{} },
+	"regex":      func(int) Matcher { return pattern{} },
+	"edit":       func(maxEdits int) Matcher { return edit{maxEdits: maxEdits} },
+}
+
+// RegisterMatcher plugs a matcher, selectable by name
+func RegisterMatcher(name string, factory MatcherFactory) {
+	matchers[name] = factory
+}
+
+// NewMatcher by name, empty name is exact
+func NewMatcher(name string, maxEdits int) (Matcher, bool) {
+	if name == "" {
+		name = "exact"
+	}
+
+	factory, ok := matchers[name]
+	if !ok {
+		return nil, false
+	}
+
+	return factory(maxEdits), true
+}
+
+// MatcherNames sorted alphabetically
+func MatcherNames() []string {
+	names := make([]string, 0, len(matchers))
+	for name := range matchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// exact match after trimming whitespace
+type exact struct{}
+
+func (m exact) Match(prediction, answer string) bool {
+	return strings.TrimSpace(prediction) == strings.TrimSpace(answer)
+}
+
+// normalized match, insensitive to case, diacritics and repeated whitespace
+type normalized struct{}
+
+func (m normalized) Match(prediction, answer string) bool {
+	return normalize(prediction) == normalize(answer)
+}
+
+// numeric match, insensitive to thousand separators and decimal comma
+type numeric struct{}
+
+func (m numeric) Match(prediction, answer string) bool {
+	p, err := parseNumber(prediction)
+	if err != nil {
+		return false
+	}
+	a, err := parseNumber(answer)
+	if err != nil {
+		return false
+	}
+
+	return p == a
+}
+
+func (m numeric) Validate(answer string) error {
+	_, err := parseNumber(answer)
+	return err
+}
+
+// pattern match, answer is a regular expression matching the whole prediction
+type pattern struct{}
+
+func (m pattern) Match(prediction, answer string) bool {
+	re, err := regexp.Compile("^(?:" + answer + ")$")
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(strings.TrimSpace(prediction))
+}
+
+func (m pattern) Validate(answer string) error {
+	_, err := regexp.Compile("^(?:" + answer + ")$")
+	return err
+}
+
+// edit match, normalized prediction is within edit distance of normalized answer
+type edit struct {
+	maxEdits int
+}
+
+func (m edit) Match(prediction, answer string) bool {
+	return levenshtein(normalize(prediction), normalize(answer)) <= m.maxEdits
+}
+
+// normalize to lower case without diacritics and repeated whitespace
+func normalize(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+
+	return strings.Join(strings.Fields(strings.ToLower(folded)), " ")
+}
+
+// parseNumber written with either dot or comma as thousand separator and the other as decimal separator
+// last separator is a thousand separator if it repeats, or it's the only kind and followed by 3 digits, e.g. 1.000
+func parseNumber(s string) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	last := strings.LastIndexAny(s, ".,")
+	if last < 0 {
+		return strconv.ParseFloat(s, 64)
+	}
+
+	sep, other := s[last:last+1], "."
+	if sep == "." {
+		other = ","
+	}
+
+	integer, fraction := s[:last], s[last+1:]
+	if strings.Count(s, sep) > 1 || (!strings.Contains(integer, other) && len(fraction) == 3) {
+		integer, fraction = s, ""
+	}
+
+	integer = strings.NewReplacer(".", "", ",", "").Replace(integer)
+	if fraction == "" {
+		return strconv.ParseFloat(integer, 64)
+	}
+
+	return strconv.ParseFloat(integer+"."+fraction, 64)
+}
+
+// levenshtein distance between two strings, counted in runes
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr := make([]int, len(rb)+1)
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+
+	return prev[len(rb)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+
+	return m
+}
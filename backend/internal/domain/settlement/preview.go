@@ -0,0 +1,123 @@
+package settlement
+
+import (
+	"context"
+	"sort"
+
+	betDao "github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
+)
+
+// Preview of settlement, computed as the job would settle the topic but nothing is written
+type Preview struct {
+	Topic      string     `json:"topic"`
+	Kind       kind       `json:"kind"`
+	Answer     *Answer    `json:"answer"` // nil when voided
+	Pool       *Pool      `json:"pool,omitempty"`
+	Difficulty int        `json:"difficulty"` // percentage of bettors who got it wrong
+	Weighted   bool       `json:"weighted"`   // reward is scaled by difficulty
+	EarlyBird  *EarlyBird `json:"early_bird,omitempty"`
+	Stat       *Settled   `json:"stat"`
+	Won        []*Payout  `json:"won"`
+	Lost       []*Payout  `json:"lost"`
+	Void       []*Payout  `json:"void"`
+	Deltas     []*Delta   `json:"deltas"` // ordered by user
+}
+
+// Delta of reputation posted to the ledger of a user
+// includes released escrow and reversal of previous settlement, if any
+type Delta struct {
+	User  string `json:"user"`
+	Delta int64  `json:"delta"`
+}
+
+// Preview settlement of a topic, e.g. to sanity check variations before answering
+// Verification:
+// 1. Same as submitting the command, except other job may be settling the topic
+// Then:
+// 1. Count parimutuel pool and difficulty
+// 2. Settle a copy of each bet as the job would
+// 3. Sum the delta of each user's ledger, previous payout is reverted as by correction
+func (svc *Service) Preview(ctx context.Context, cmd *Command) (*Preview, error) {
+	kind, err := verify(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	topic, err := svc.topics.Topic(ctx, cmd.Topic)
+	if err != nil {
+		return nil, err
+	}
+
+	ans, err := resolve(kind, cmd, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{Topic: cmd.Topic, Kind: kind, Answer: ans}
+	if err = svc.weigh(ctx, job, topic); err != nil {
+		return nil, err
+	}
+
+	preview := &Preview{
+		Topic:      cmd.Topic,
+		Kind:       kind,
+		Answer:     ans,
+		Pool:       job.Pool,
+		Difficulty: job.Difficulty,
+		Weighted:   job.Weighted,
+		EarlyBird:  job.EarlyBird,
+		Stat:       &Settled{},
+		Won:        []*Payout{},
+		Lost:       []*Payout{},
+		Void:       []*Payout{},
+		Deltas:     []*Delta{},
+	}
+	deltas := map[string]int64{}
+	var shared int64
+
+	err = svc.eachBet(ctx, map[string]interface{}{
+		"topic_id": cmd.Topic,
+		"state":    map[string]interface{}{"$in": settleable()},
+	}, func(bet *betDao.Bet) {
+		previous := paid(bet)
+		job.apply(bet)
+
+		payout := &Payout{
+			Bet:   bet.ID.Hex(),
+			User:  bet.Owner,
+			State: string(bet.State),
+			Stake: int64(bet.Reputation),
+			Net:   bet.Payout - bet.Escrow,
+		}
+		switch bet.State {
+		case betDao.BetStates.Won():
+			preview.Stat.Won++
+			preview.Won = append(preview.Won, payout)
+			shared += payout.Net
+		case betDao.BetStates.Lost():
+			preview.Stat.Lost++
+			preview.Lost = append(preview.Lost, payout)
+		case betDao.BetStates.Void():
+			preview.Stat.Void++
+			preview.Void = append(preview.Void, payout)
+		}
+
+		deltas[bet.Owner] += bet.Payout - previous
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Stat.Total = preview.Stat.Won + preview.Stat.Lost + preview.Stat.Void
+	if job.Pool != nil {
+		preview.Stat.Burned = job.Pool.Losers - shared
+	}
+	for user, delta := range deltas {
+		preview.Deltas = append(preview.Deltas, &Delta{User: user, Delta: delta})
+	}
+	sort.Slice(preview.Deltas, func(i, j int) bool {
+		return preview.Deltas[i].User < preview.Deltas[j].User
+	})
+
+	return preview, nil
+}
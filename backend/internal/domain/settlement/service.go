@@ -0,0 +1,778 @@
+// Package settlement settles bets of an answered or voided topic and rewards karma through the ledger
+package settlement
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	betDao "github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
+	"github.com/di-collective/ditebak/backend/internal/domain/ledger"
+	topicDao "github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
+	topicService "github.com/di-collective/ditebak/backend/internal/domain/topic/service"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	typedRepo "github.com/di-collective/ditebak/backend/pkg/repo/typed"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Settled statistics
+type Settled struct {
+	Lost   int   `json:"lost"`
+	Won    int   `json:"won"`
+	Void   int   `json:"void"`
+	Total  int   `json:"total"`
+	Burned int64 `json:"burned"` // losers' stake not paid to winners in parimutuel
+}
+
+// Jobs repository, progress of a running job is mutated atomically and only by its runner
+type Jobs interface {
+	repo.Repository
+	repo.Mutator
+}
+
+// Service of settlement
+type Service struct {
+	tx       repo.Transactor
+	topics   *topicService.Service
+	bets     typedRepo.Repository[*betDao.Bet, *betDao.Bet]
+	ledger   *ledger.Service
+	jobs     Jobs
+	attempts int
+	runner   string        // identity of this replica, set as runner of the jobs it runs
+	stale    time.Duration // job left unsaved for longer is claimed by another replica
+}
+
+// New settlement service
+// @tx: runs each step of settlement as a transaction
+// @topics: topic service, enforces topic state
+// @bets: bet repository
+// @ledger: posts reputation of bet owners
+// @jobs: settlement job repository
+func New(tx repo.Transactor, topics *topicService.Service, bets typedRepo.Repository[*betDao.Bet, *betDao.Bet], ledger *ledger.Service, jobs Jobs) *Service {
+	host, _ := os.Hostname()
+	return &Service{
+		tx:       tx,
+		topics:   topics,
+		bets:     bets,
+		ledger:   ledger,
+		jobs:     jobs,
+		attempts: 3,
+		runner:   fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano()),
+		stale:    5 * time.Minute,
+	}
+}
+
+// Submit settlement of a topic, bets are settled asynchronously
+// Verification:
+// 1. Kind must be known and topic can't be empty
+// 2. Answer can't be empty unless voided, reason can't be empty unless answered
+// 3. No other job is settling the topic
+// 4. Topic must be closed to be answered, answered to be corrected, enforced by topic service
+// 5. Answer of multiple-choice topic must be one of its option ID, of number or date topic must be parsable,
+// of probability topic must be yes or no, of free text topic must be accepted by its matcher
+// Then:
+// 1. Move topic to answered or voided, store the answer and create the job in a single transaction
+// 2. Count parimutuel pool and difficulty up front, bets of a closed topic don't change
+// 3. Run the job in background
+func (svc *Service) Submit(ctx context.Context, cmd *Command, by string) (*Job, error) {
+	kind, err := verify(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{}
+	err = svc.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := svc.idle(ctx, cmd.Topic); err != nil {
+			return err
+		}
+
+		previous, err := svc.topics.Topic(ctx, cmd.Topic)
+		if err != nil {
+			return err
+		}
+
+		answered := previous.State == topicDao.TopicStates.Answered()
+		ans, err := resolve(kind, cmd, previous)
+		if err != nil {
+			return err
+		}
+
+		to := topicDao.TopicStates.Answered()
+		if kind == JobKinds.Void() {
+			to = topicDao.TopicStates.Voided()
+		}
+
+		*job = Job{
+			By:          by,
+			Topic:       cmd.Topic,
+			Kind:        kind,
+			Answer:      ans,
+			Reason:      cmd.Reason,
+			State:       JobStates.Pending(),
+			Runner:      svc.runner,
+			Stat:        &Settled{},
+			Payouts:     []*Payout{},
+			DeadLetters: []*DeadLetter{},
+		}
+		if answered {
+			job.Previous = previous.Answer
+		}
+		if err = svc.weigh(ctx, job, previous); err != nil {
+			return err
+		}
+		if err = svc.jobs.Create(ctx, job); err != nil {
+			return err
+		}
+
+		if _, err = svc.topics.Transition(ctx, cmd.Topic, string(to), by); err != nil {
+			return err
+		}
+
+		answer, difficulty := "", (*int)(nil)
+		if ans != nil {
+			answer, difficulty = ans.Answer, &job.Difficulty
+		}
+		_, err = svc.topics.Answer(ctx, cmd.Topic, answer, difficulty, job.ID.Hex())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	running := *job // job is returned to caller while running
+	go svc.run(context.Background(), &running)
+	return job, nil
+}
+
+// Matched bets by a matcher
+type Matched struct {
+	Matcher  string        `json:"matcher"`
+	Accepted int           `json:"accepted"`
+	Rejected int           `json:"rejected"`
+	Bets     []*MatchedBet `json:"bets"` // accepted bets
+}
+
+// MatchedBet accepted by a matcher
+type MatchedBet struct {
+	ID         string `json:"id"`
+	Owner      string `json:"owner"`
+	Prediction string `json:"prediction"`
+}
+
+// DryRun every matcher against bets of a free text topic, nothing is changed
+// answer and variations are matched as if the topic is answered with them
+func (svc *Service) DryRun(ctx context.Context, ans *Answer) ([]*Matched, error) {
+	if ans.Topic == "" {
+		return nil, exception.New(http.StatusBadRequest, "Topic can't be empty")
+	}
+	if strings.TrimSpace(ans.Answer) == "" {
+		return nil, exception.New(http.StatusBadRequest, "Answer can't be empty")
+	}
+
+	topic, err := svc.topics.Topic(ctx, ans.Topic)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(topic.Options) > 0 || topic.Kind.Scored() || topic.Kind == topicDao.PredictionKinds.Probability() {
+		return nil, exception.New(http.StatusBadRequest, "Only free text topic is matched")
+	}
+
+	results := []*Matched{}
+	for _, name := range MatcherNames() {
+		try := *ans
+		try.Choice, try.Kind, try.Matcher, try.MaxEdits = false, "", name, topic.MaxEdits
+		result := &Matched{Matcher: name, Bets: []*MatchedBet{}}
+		results = append(results, result)
+		if validateAnswer(&try) != nil {
+			continue // answer is unusable by the matcher, e.g. not a regex
+		}
+
+		err := svc.eachBet(ctx, map[string]interface{}{
+			"topic_id": topic.ID.Hex(),
+			"state":    map[string]interface{}{"$in": settleable()},
+		}, func(bet *betDao.Bet) {
+			if !try.IsTrue(bet.Prediction) {
+				result.Rejected++
+				return
+			}
+
+			result.Accepted++
+			result.Bets = append(result.Bets, &MatchedBet{
+				ID:         bet.ID.Hex(),
+				Owner:      bet.Owner,
+				Prediction: bet.Prediction,
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// Get a job
+func (svc *Service) Get(ctx context.Context, id string) (*Job, error) {
+	res, err := svc.jobs.Get(ctx, id)
+	if err == mongo.ErrNoDocuments {
+		return nil, exception.New(http.StatusNotFound, "Settlement job with ID: %s, is not found", id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return res.(*Job), nil
+}
+
+// Retry a failed job, dead letters are given another chance
+func (svc *Service) Retry(ctx context.Context, id string) (*Job, error) {
+	job, err := svc.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.State != JobStates.Failed() {
+		return nil, exception.New(http.StatusConflict, "Only failed job can be retried, job is %s", job.State)
+	}
+
+	topic, err := svc.topics.Topic(ctx, job.Topic)
+	if err != nil {
+		return nil, err
+	}
+	if current := topic.Settlement; current != id {
+		return nil, exception.New(http.StatusConflict, "Job is superseded by settlement job %s", current)
+	}
+
+	job.State = JobStates.Pending()
+	job.Runner = svc.runner
+	job.Error = ""
+	job.DeadLetters = []*DeadLetter{}
+	if err = svc.jobs.Update(ctx, id, job); err != nil {
+		return nil, err
+	}
+
+	running := *job // job is returned to caller while running
+	go svc.run(context.Background(), &running)
+	return job, nil
+}
+
+// Resume unfinished jobs which aren't run by any live replica, e.g. after restart
+// every job is found before any of them runs, so jobs finishing meanwhile don't shift the pages
+// safe to run on multiple replicas and repeatedly, each job is claimed by only one of them before it runs
+func (svc *Service) Resume(ctx context.Context) error {
+	opt := repo.FindOptions{
+		Page: 1,
+		Size: 100,
+		Params: map[string]interface{}{
+			"state": map[string]interface{}{
+				"$in": []interface{}{JobStates.Pending(), JobStates.Running()},
+			},
+		},
+	}
+
+	jobs := []*Job{}
+	for {
+		total, rows, err := svc.jobs.Find(ctx, opt)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			jobs = append(jobs, row.(*Job))
+		}
+
+		if len(rows) <= 0 || int64(opt.Page*opt.Size) >= total {
+			break
+		}
+		opt.Page++
+	}
+
+	for _, job := range jobs {
+		claimed, err := svc.claim(ctx, job)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		log.Infof("Resuming settlement job %s of topic %s", job.ID.Hex(), job.Topic)
+		go svc.run(ctx, job)
+	}
+
+	return nil
+}
+
+// claim an unfinished job for this replica, job is refreshed by the stored one
+// returns false if it's finished meanwhile, or run by another replica which saved it lately
+// job without runner is left by older version, job of this replica is still run by it
+func (svc *Service) claim(ctx context.Context, job *Job) (bool, error) {
+	now := time.Now()
+	res, err := svc.jobs.Mutate(ctx, job.ID.Hex(), repo.Mutation{
+		If: map[string]interface{}{
+			"state": map[string]interface{}{
+				"$in": []interface{}{JobStates.Pending(), JobStates.Running()},
+			},
+			"$or": []interface{}{
+				map[string]interface{}{"runner": nil},
+				map[string]interface{}{
+					"runner":     map[string]interface{}{"$ne": svc.runner},
+					"updated_at": map[string]interface{}{"$lt": now.Add(-svc.stale)},
+				},
+			},
+		},
+		Set: map[string]interface{}{"runner": svc.runner, "updated_at": &now},
+	})
+	if err == repo.ErrUnmatched {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	*job = *res.(*Job)
+	return true, nil
+}
+
+// run the job until every bet of the topic is settled by it
+// Process:
+// 1. Find a page of bets not yet settled by this job, excluding dead letters
+// 2. Settle each bet in its own transaction, retry before parking it as dead letter
+// 3. Repeat until no more bets left
+// 4. Tally final stat and payout per user from settled bets
+// progress is mutated atomically and job is refreshed by it, every save keeps the job claimed by this replica
+// run stops once the job is claimed by another replica, e.g. when it's stale
+func (svc *Service) run(ctx context.Context, job *Job) {
+	id := job.ID.Hex()
+	svc.progress(ctx, job, repo.Mutation{Set: map[string]interface{}{"state": JobStates.Running()}})
+
+	for {
+		if job.Runner != svc.runner {
+			log.Warnf("Settlement job %s is claimed by %s, stop running it", id, job.Runner)
+			return
+		}
+
+		bets, err := svc.pendingBets(ctx, job)
+		if err != nil {
+			svc.stop(ctx, job, err)
+			return
+		}
+
+		// no more bets
+		if len(bets) <= 0 {
+			break
+		}
+
+		for _, bet := range bets {
+			var settled bool
+			var err error
+			for attempt := 1; attempt <= svc.attempts; attempt++ {
+				if settled, err = svc.settle(ctx, job, bet.ID.Hex()); err == nil {
+					break
+				}
+
+				log.Warnf("Failed to settle bet %s of job %s, attempt: %d, err: %v", bet.ID.Hex(), id, attempt, err)
+			}
+
+			if err != nil {
+				now := time.Now()
+				dl := &DeadLetter{
+					Bet:      bet.ID.Hex(),
+					Error:    err.Error(),
+					Attempts: svc.attempts,
+					At:       &now,
+				}
+				if !svc.progress(ctx, job, repo.Mutation{Push: map[string]interface{}{"dead_letters": dl}}) {
+					job.DeadLetters = append(job.DeadLetters, dl) // still skipped by next round
+				}
+			} else if settled {
+				svc.progress(ctx, job, repo.Mutation{Inc: map[string]int64{"processed": 1}})
+			}
+		}
+	}
+
+	if job.Runner != svc.runner {
+		log.Warnf("Settlement job %s is claimed by %s, stop running it", id, job.Runner)
+		return
+	}
+
+	stat, payouts, err := svc.tally(ctx, job)
+	if err != nil {
+		svc.stop(ctx, job, err)
+		return
+	}
+
+	now := time.Now()
+	stat.Total += len(job.DeadLetters)
+	done := map[string]interface{}{
+		"stat":        stat,
+		"payouts":     payouts,
+		"finished_at": &now,
+		"state":       JobStates.Done(),
+	}
+	if len(job.DeadLetters) > 0 {
+		done["state"] = JobStates.Failed()
+		done["error"] = "Some bets can't be settled"
+	}
+
+	svc.progress(ctx, job, repo.Mutation{Set: done})
+	log.Infof("Settlement job %s is %s, stat: %+v", id, job.State, job.Stat)
+}
+
+// settle one bet, skipped if already settled by the job, e.g. by another replica running it
+// returns true only if the bet is settled by this call
+// Process:
+// 1. Revert payout of previous settlement, if any
+// 2. Pay the outcome of this job and release escrowed stake, only refund if voided
+// 3. Update bet and post both to the ledger of its owner in a single transaction
+func (svc *Service) settle(ctx context.Context, job *Job, id string) (bool, error) {
+	settled := false
+	err := svc.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		settled = false // transaction may be retried
+		bet, err := svc.bets.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if bet.Settlement == job.ID.Hex() {
+			return nil // already settled
+		}
+
+		reversal := &ledger.Entry{
+			User:       bet.Owner,
+			Delta:      -paid(bet),
+			Reason:     ledger.Reasons.Reversal(),
+			Topic:      bet.TopicID,
+			Bet:        id,
+			Settlement: bet.Settlement,
+		}
+		outcome := &ledger.Entry{
+			User:       bet.Owner,
+			Topic:      bet.TopicID,
+			Bet:        id,
+			Settlement: job.ID.Hex(),
+		}
+
+		job.apply(bet)
+		switch bet.State {
+		case betDao.BetStates.Void():
+			outcome.Reason = ledger.Reasons.Refund()
+		case betDao.BetStates.Won():
+			outcome.Reason = ledger.Reasons.Won()
+		default:
+			outcome.Reason = ledger.Reasons.Lost()
+		}
+		bet.Settlement = job.ID.Hex()
+		outcome.Delta = bet.Payout
+
+		if err = svc.bets.Update(ctx, id, bet); err != nil {
+			return err
+		}
+		if err = svc.ledger.Post(ctx, reversal, outcome); err != nil {
+			return err
+		}
+
+		settled = true
+		return nil
+	})
+
+	return settled && err == nil, err
+}
+
+// paid reputation to the owner of a bet by its last settlement
+func paid(bet *betDao.Bet) int64 {
+	if bet.Settlement != "" {
+		return bet.Payout
+	}
+
+	// settled before payout is recorded
+	switch bet.State {
+	case betDao.BetStates.Won():
+		return int64(bet.Reputation)
+	case betDao.BetStates.Lost():
+		return -int64(bet.Reputation)
+	}
+
+	return 0
+}
+
+// pendingBets on the topic of the job which are not settled by it, excluding its dead letters
+func (svc *Service) pendingBets(ctx context.Context, job *Job) ([]*betDao.Bet, error) {
+	_, bets, err := svc.bets.Find(ctx, repo.FindOptions{
+		Page: 1,
+		Size: 100,
+		Params: map[string]interface{}{
+			"topic_id":   job.Topic,
+			"settlement": map[string]interface{}{"$ne": job.ID.Hex()},
+			"state":      map[string]interface{}{"$in": settleable()},
+			"_id":        map[string]interface{}{"$nin": job.deadBets()},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bets, nil
+}
+
+// tally bets settled by the job
+func (svc *Service) tally(ctx context.Context, job *Job) (*Settled, []*Payout, error) {
+	stat := &Settled{}
+	payouts := []*Payout{}
+	var shared int64
+
+	err := svc.eachBet(ctx, map[string]interface{}{
+		"topic_id":   job.Topic,
+		"settlement": job.ID.Hex(),
+	}, func(bet *betDao.Bet) {
+		net := bet.Payout - bet.Escrow
+		switch bet.State {
+		case betDao.BetStates.Won():
+			stat.Won++
+			shared += net
+		case betDao.BetStates.Lost():
+			stat.Lost++
+		case betDao.BetStates.Void():
+			stat.Void++
+		}
+
+		payouts = append(payouts, &Payout{
+			Bet:   bet.ID.Hex(),
+			User:  bet.Owner,
+			State: string(bet.State),
+			Stake: int64(bet.Reputation),
+			Net:   net,
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stat.Total = stat.Won + stat.Lost + stat.Void
+	if job.Pool != nil {
+		stat.Burned = job.Pool.Losers - shared
+	}
+
+	return stat, payouts, nil
+}
+
+// weigh bets on the topic by the answer of the job, nothing to weigh if voided
+// difficulty is the percentage of bettors who got it wrong, stakes are split into parimutuel pool
+// bonus of flat payout is snapshotted, topic can't be edited once closed anyway
+func (svc *Service) weigh(ctx context.Context, job *Job, topic *topicDao.Topic) error {
+	if job.Kind == JobKinds.Void() {
+		return nil
+	}
+
+	pool, won, lost := &Pool{Burn: topic.Burn}, 0, 0
+	err := svc.eachBet(ctx, map[string]interface{}{
+		"topic_id": job.Topic,
+		"state":    map[string]interface{}{"$in": settleable()},
+	}, func(bet *betDao.Bet) {
+		if job.Answer.Score(bet) > 0 {
+			won++
+			pool.Winners += int64(bet.Reputation)
+		} else {
+			lost++
+			pool.Losers += int64(bet.Reputation)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	job.Difficulty = difficulty(won, lost)
+	if topic.PayoutMode == topicDao.PayoutModes.Parimutuel() {
+		job.Pool = pool
+		return nil
+	}
+
+	job.Weighted = topic.Weighted
+	if topic.EarlyBird > 0 {
+		job.EarlyBird = &EarlyBird{Bonus: topic.EarlyBird, Opening: topic.CreatedAt, Closing: topic.ClosingAt}
+	}
+
+	return nil
+}
+
+// difficulty is the percentage of bettors who lost, rounded down, 0 without any bettor
+func difficulty(won, lost int) int {
+	if won+lost <= 0 {
+		return 0
+	}
+
+	return lost * 100 / (won + lost)
+}
+
+// eachBet matching params, page by page
+func (svc *Service) eachBet(ctx context.Context, params map[string]interface{}, fn func(*betDao.Bet)) error {
+	opt := repo.FindOptions{Page: 1, Size: 100, Params: params}
+	for {
+		total, bets, err := svc.bets.Find(ctx, opt)
+		if err != nil {
+			return err
+		}
+
+		for _, bet := range bets {
+			fn(bet)
+		}
+
+		if len(bets) <= 0 || int64(opt.Page*opt.Size) >= total {
+			return nil
+		}
+		opt.Page++
+	}
+}
+
+// settleable states of bet, settled bet can be settled again by correction
+func settleable() []interface{} {
+	return []interface{}{
+		betDao.BetStates.Placed(),
+		betDao.BetStates.Won(),
+		betDao.BetStates.Lost(),
+		betDao.BetStates.Void(),
+	}
+}
+
+// verify command and parse its kind
+func verify(cmd *Command) (kind, error) {
+	kind, ok := JobKinds.Parse(cmd.Kind)
+	if !ok {
+		return "", exception.New(http.StatusBadRequest, "Unknown settlement kind: %s", cmd.Kind)
+	}
+	if cmd.Topic == "" {
+		return "", exception.New(http.StatusBadRequest, "Topic can't be empty")
+	}
+	if kind != JobKinds.Void() && strings.TrimSpace(cmd.Answer.Answer) == "" {
+		return "", exception.New(http.StatusBadRequest, "Answer can't be empty")
+	}
+	if kind != JobKinds.Answer() && strings.TrimSpace(cmd.Reason) == "" {
+		return "", exception.New(http.StatusBadRequest, "Reason is required to %s a topic", kind)
+	}
+
+	return kind, nil
+}
+
+// resolve answer of the command against current state and kind of the topic, nil when voided
+func resolve(kind kind, cmd *Command, topic *topicDao.Topic) (*Answer, error) {
+	answered := topic.State == topicDao.TopicStates.Answered()
+	if kind == JobKinds.Answer() && answered {
+		return nil, exception.New(http.StatusConflict, "Topic is already answered, correct it instead")
+	}
+	if kind == JobKinds.Correct() && !answered {
+		return nil, exception.New(http.StatusConflict, "Only answered topic can be corrected, topic is %s", topic.State)
+	}
+
+	ans := &cmd.Answer
+	if kind == JobKinds.Void() {
+		return nil, nil
+	} else if len(topic.Options) > 0 {
+		if !topic.HasOption(ans.Answer) {
+			return nil, exception.New(http.StatusBadRequest, "Answer must be an option ID of the topic, got %s", ans.Answer)
+		}
+		ans.Choice, ans.Variations = true, nil
+	} else if topic.Kind == topicDao.PredictionKinds.Probability() {
+		if _, ok := topicDao.ParseOutcome(ans.Answer); !ok {
+			return nil, exception.New(http.StatusBadRequest, "Answer must be yes or no, got %s", ans.Answer)
+		}
+		ans.Kind, ans.Rule, ans.Variations = string(topic.Kind), string(topic.Rule), nil
+	} else if topic.Kind.Scored() {
+		if _, err := topic.Kind.Value(ans.Answer); err != nil {
+			return nil, exception.New(http.StatusBadRequest, "Answer must be a %s, got %s", topic.Kind, ans.Answer)
+		}
+		ans.Kind, ans.Bands, ans.Variations = string(topic.Kind), topic.Bands, nil
+		if len(ans.Bands) == 0 {
+			ans.Bands = topicDao.ExactBands()
+		}
+	} else {
+		ans.Matcher, ans.MaxEdits = topic.Matcher, topic.MaxEdits
+		if err := validateAnswer(ans); err != nil {
+			return nil, err
+		}
+	}
+
+	return ans, nil
+}
+
+// validateAnswer of free text topic against its matcher
+func validateAnswer(ans *Answer) error {
+	matcher, ok := NewMatcher(ans.Matcher, ans.MaxEdits)
+	if !ok {
+		return exception.New(http.StatusBadRequest, "Unknown matcher: %s", ans.Matcher)
+	}
+
+	validator, ok := matcher.(Validator)
+	if !ok {
+		return nil
+	}
+	for _, answer := range append([]string{ans.Answer}, ans.Variations...) {
+		if err := validator.Validate(answer); err != nil {
+			return exception.New(http.StatusBadRequest, "Answer %s is not accepted by %s matcher: %v", answer, ans.Matcher, err)
+		}
+	}
+
+	return nil
+}
+
+// idle verifies no unfinished job is settling the topic, otherwise both jobs race on its bets
+func (svc *Service) idle(ctx context.Context, topic string) error {
+	total, _, err := svc.jobs.Find(ctx, repo.FindOptions{
+		Page: 1,
+		Size: 1,
+		Params: map[string]interface{}{
+			"topic": topic,
+			"state": map[string]interface{}{
+				"$in": []interface{}{JobStates.Pending(), JobStates.Running()},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if total > 0 {
+		return exception.New(http.StatusConflict, "Topic %s is still being settled", topic)
+	}
+
+	return nil
+}
+
+// stop the job because of an error, it can be retried
+func (svc *Service) stop(ctx context.Context, job *Job, err error) {
+	log.Errorf("Settlement job %s stopped, err: %v", job.ID.Hex(), err)
+	svc.progress(ctx, job, repo.Mutation{Set: map[string]interface{}{
+		"state": JobStates.Failed(),
+		"error": err.Error(),
+	}})
+}
+
+// progress of the job mutated atomically, only if it's still claimed by this replica
+// job is refreshed by the stored one, returns false if it isn't saved
+// job is then left untouched, unless claimed by another replica which is then its runner
+func (svc *Service) progress(ctx context.Context, job *Job, m repo.Mutation) bool {
+	now := time.Now()
+	set := map[string]interface{}{"updated_at": &now}
+	for field, value := range m.Set {
+		set[field] = value
+	}
+	m.Set = set
+	m.If = map[string]interface{}{"runner": svc.runner}
+
+	res, err := svc.jobs.Mutate(ctx, job.ID.Hex(), m)
+	if err == repo.ErrUnmatched {
+		if res, err = svc.jobs.Get(ctx, job.ID.Hex()); err == nil {
+			job.Runner = res.(*Job).Runner
+		}
+		return false
+	} else if err != nil {
+		log.Errorf("Failed to save settlement job %s, err: %v", job.ID.Hex(), err)
+		return false
+	}
+
+	*job = *res.(*Job)
+	return true
+}
@@ -0,0 +1,91 @@
+package settlement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/memrepo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/repotest"
+)
+
+// replica running settlement jobs, only its jobs are needed
+func replica(jobs Jobs, runner string) *Service {
+	return &Service{jobs: jobs, runner: runner, stale: time.Minute}
+}
+
+func TestClaim(t *testing.T) {
+	live, stale := time.Now(), time.Now().Add(-2*time.Minute)
+	tests := []struct {
+		name      string
+		state     state
+		runner    string
+		updatedAt time.Time
+		want      bool
+	}{
+		{"without runner", JobStates.Pending(), "", live, true},
+		{"stale of another replica", JobStates.Running(), "b", stale, true},
+		{"live of another replica", JobStates.Running(), "b", live, false},
+		{"stale of this replica", JobStates.Running(), "a", stale, false},
+		{"done", JobStates.Done(), "", stale, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			jobs := memrepo.New(memrepo.NewStore(), "jobs", nil, func() interface{} { return &Job{} }, &repotest.Delegate{})
+			job := &Job{ID: primitive.NewObjectID(), UpdatedAt: &tt.updatedAt, State: tt.state, Runner: tt.runner}
+			if err := jobs.Create(ctx, job); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			got, err := replica(jobs, "a").claim(ctx, job)
+			if err != nil {
+				t.Fatalf("claim() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("claim() = %v, want %v", got, tt.want)
+			}
+			if got && job.Runner != "a" {
+				t.Errorf("claimed job runner = %s, want a", job.Runner)
+			}
+
+			// another replica resuming at the same time loses it, if claimed
+			if again, err := replica(jobs, "c").claim(ctx, job); err != nil || (again && got) {
+				t.Errorf("claim() by another replica = %v, %v, want false", again, err)
+			}
+		})
+	}
+}
+
+func TestProgress(t *testing.T) {
+	ctx := context.Background()
+	jobs := memrepo.New(memrepo.NewStore(), "jobs", nil, func() interface{} { return &Job{} }, &repotest.Delegate{})
+	job := &Job{ID: primitive.NewObjectID(), State: JobStates.Running(), Runner: "a"}
+	if err := jobs.Create(ctx, job); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	a, b := replica(jobs, "a"), replica(jobs, "b")
+	if !a.progress(ctx, job, repo.Mutation{Inc: map[string]int64{"processed": 1}}) || job.Processed != 1 {
+		t.Fatalf("progress() by runner = %d processed, want 1", job.Processed)
+	}
+
+	// job is claimed by b, e.g. a stalled long enough
+	if _, err := jobs.Mutate(ctx, job.ID.Hex(), repo.Mutation{Set: map[string]interface{}{"runner": "b"}}); err != nil {
+		t.Fatalf("Mutate() error = %v", err)
+	}
+
+	if a.progress(ctx, job, repo.Mutation{Inc: map[string]int64{"processed": 1}}) {
+		t.Errorf("progress() by former runner = true, want false")
+	}
+	if job.Runner != "b" || job.Processed != 1 {
+		t.Errorf("job = %s with %d processed, want b with 1", job.Runner, job.Processed)
+	}
+	if !b.progress(ctx, job, repo.Mutation{Inc: map[string]int64{"processed": 1}}) || job.Processed != 2 {
+		t.Errorf("progress() by new runner = %d processed, want 2", job.Processed)
+	}
+}
@@ -1,6 +1,10 @@
 package dao
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,6 +23,16 @@ type states interface {
 	Published() state
 	Closed() state
 	Answered() state
+	Voided() state
+	Parse(string) (state, bool)
+}
+
+// transitions allowed from each state
+var transitions = map[state][]state{
+	enum.Draft():     {enum.Published()},
+	enum.Published(): {enum.Closed()},
+	enum.Closed():    {enum.Answered(), enum.Voided()},
+	enum.Answered():  {enum.Answered(), enum.Voided()}, // answer can be corrected
 }
 
 func (t *state) Draft() state {
@@ -37,15 +51,270 @@ func (t *state) Answered() state {
 	return state("answered")
 }
 
+// Voided topic has no valid answer, all stakes are refunded
+func (t *state) Voided() state {
+	return state("voided")
+}
+
+// Parse string into known state
+func (t *state) Parse(s string) (state, bool) {
+	switch st := state(s); st {
+	case t.Draft(), t.Published(), t.Closed(), t.Answered(), t.Voided():
+		return st, true
+	}
+
+	return state(""), false
+}
+
+// CanTransition check whether topic may move from one state to another
+func CanTransition(from, to state) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Payout modes
+var (
+	modeEnum          = mode("")
+	PayoutModes modes = &modeEnum
+)
+
+type mode string
+
+type modes interface {
+	Flat() mode
+	Parimutuel() mode
+	Parse(string) (mode, bool)
+}
+
+// Flat payout, winners get their stake and losers lose theirs
+func (t *mode) Flat() mode {
+	return mode("flat")
+}
+
+// Parimutuel payout, losers' pool is shared among winners proportionally to their stake
+func (t *mode) Parimutuel() mode {
+	return mode("parimutuel")
+}
+
+// Parse string into known mode, empty string is flat
+func (t *mode) Parse(s string) (mode, bool) {
+	switch m := mode(s); m {
+	case "":
+		return t.Flat(), true
+	case t.Flat(), t.Parimutuel():
+		return m, true
+	}
+
+	return mode(""), false
+}
+
+// Transition record of topic state
+type Transition struct {
+	From state      `json:"from" bson:"from"`
+	To   state      `json:"to" bson:"to"`
+	By   string     `json:"by" bson:"by"` // who made the transition (email)
+	At   *time.Time `json:"at" bson:"at"`
+}
+
+// Prediction kinds
+var (
+	kindEnum              = kind("")
+	PredictionKinds kinds = &kindEnum
+)
+
+type kind string
+
+type kinds interface {
+	Text() kind
+	Number() kind
+	Date() kind
+	Probability() kind
+	Parse(string) (kind, bool)
+}
+
+// Text prediction is matched exactly, or by option ID
+func (t *kind) Text() kind {
+	return kind("text")
+}
+
+// Number prediction is scored by its distance to the answer
+func (t *kind) Number() kind {
+	return kind("number")
+}
+
+// Date prediction (YYYY-MM-DD or RFC3339) is scored by its distance to the answer in days
+func (t *kind) Date() kind {
+	return kind("date")
+}
+
+// Probability of yes/no topic answered yes, between 0 and 100, scored by a proper scoring rule
+func (t *kind) Probability() kind {
+	return kind("probability")
+}
+
+// Parse string into known kind, empty string is text
+func (t *kind) Parse(s string) (kind, bool) {
+	switch k := kind(s); k {
+	case "":
+		return t.Text(), true
+	case t.Text(), t.Number(), t.Date(), t.Probability():
+		return k, true
+	}
+
+	return kind(""), false
+}
+
+// Scored by distance instead of exact match
+func (k kind) Scored() bool {
+	return k == kindEnum.Number() || k == kindEnum.Date()
+}
+
+// Value of prediction or answer of scored kind, date is counted in days since epoch
+// number must be finite, NaN or Inf has no distance to anything
+func (k kind) Value(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if k != kindEnum.Date() {
+		v, err := strconv.ParseFloat(s, 64)
+		if err == nil && (math.IsNaN(v) || math.IsInf(v, 0)) {
+			return 0, fmt.Errorf("%s is not a finite number", s)
+		}
+		return v, err
+	}
+
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339, s); err != nil {
+			return 0, err
+		}
+	}
+
+	return float64(t.Unix()) / (24 * 60 * 60), nil
+}
+
+// Scoring rules of probability
+var (
+	ruleEnum           = rule("")
+	ScoringRules rules = &ruleEnum
+)
+
+type rule string
+
+type rules interface {
+	Brier() rule
+	Log() rule
+	Parse(string) (rule, bool)
+}
+
+// Brier scores squared error of the probability
+func (t *rule) Brier() rule {
+	return rule("brier")
+}
+
+// Log scores logarithm of the probability given to the outcome
+func (t *rule) Log() rule {
+	return rule("log")
+}
+
+// Parse string into known rule, empty string is brier
+func (t *rule) Parse(s string) (rule, bool) {
+	switch r := rule(s); r {
+	case "":
+		return t.Brier(), true
+	case t.Brier(), t.Log():
+		return r, true
+	}
+
+	return rule(""), false
+}
+
+// ParseOutcome of yes/no topic
+func ParseOutcome(s string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "true":
+		return true, true
+	case "no", "false":
+		return false, true
+	}
+
+	return false, false
+}
+
+// Band of tolerance, prediction within distance of the answer is rewarded a percentage of its stake
+// negative reward loses part of the stake, -100 loses all of it
+type Band struct {
+	Within float64 `json:"within" bson:"within"`
+	Reward int     `json:"reward" bson:"reward"`
+}
+
+// ExactBands rewards only exact prediction, default of scored topic
+func ExactBands() []*Band {
+	return []*Band{{Within: 0, Reward: 100}}
+}
+
+// EarlyBonus percentage of prediction placed at the time, decays linearly from max at opening to 0 at closing
+func EarlyBonus(max int, opening, closing, at *time.Time) int {
+	if max <= 0 || opening == nil || closing == nil || at == nil || !closing.After(*opening) {
+		return 0
+	}
+	if at.Before(*opening) {
+		return max
+	}
+	if !at.Before(*closing) {
+		return 0
+	}
+
+	// in float, max times nanoseconds left overflows int64 on topics open for years
+	return int(float64(max) * float64(closing.Sub(*at)) / float64(closing.Sub(*opening)))
+}
+
+// Option of multiple-choice topic, bets predict and topic is answered by option ID
+type Option struct {
+	ID          string `json:"id" bson:"id"`
+	Label       string `json:"label" bson:"label"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+}
+
 // Topic database object
 type Topic struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	CreatedAt *time.Time         `json:"created_at" bson:"created_at,omitempty"`
-	UpdatedAt *time.Time         `json:"updated_at" bson:"updated_at,omitempty"`
-	ClosingAt *time.Time         `json:"closing_at" bson:"closing_at"`
-	Banner    string             `json:"banner" bson:"banner"`
-	Question  string             `json:"question" bson:"question"`
-	Answer    string             `json:"answer" bson:"answer"`
-	Context   string             `json:"context" bson:"context"`
-	State     state              `json:"state" bson:"state"`
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CreatedAt  *time.Time         `json:"created_at" bson:"created_at,omitempty"`
+	UpdatedAt  *time.Time         `json:"updated_at" bson:"updated_at,omitempty"`
+	ClosingAt  *time.Time         `json:"closing_at" bson:"closing_at"`
+	PublishAt  *time.Time         `json:"publish_at" bson:"publish_at,omitempty"` // scheduled publishing of draft
+	Author     string             `json:"author" bson:"author,omitempty"`         // who drafted the topic (email)
+	Banner     string             `json:"banner" bson:"banner"`
+	Question   string             `json:"question" bson:"question"`
+	Answer     string             `json:"answer" bson:"answer"`
+	Context    string             `json:"context" bson:"context"`
+	State      state              `json:"state" bson:"state"`
+	Settlement string             `json:"settlement,omitempty" bson:"settlement,omitempty"` // ID of current settlement job
+	PayoutMode mode               `json:"payout_mode" bson:"payout_mode,omitempty"`         // empty is flat
+	Burn       int                `json:"burn" bson:"burn,omitempty"`                       // percentage of losers' pool taken by house in parimutuel
+	Weighted   bool               `json:"weighted" bson:"weighted,omitempty"`               // reward is scaled by difficulty, flat payout only
+	EarlyBird  int                `json:"early_bird" bson:"early_bird,omitempty"`           // bonus percentage of earliest prediction, flat payout only
+	Difficulty *int               `json:"difficulty,omitempty" bson:"difficulty"`           // percentage of bettors who got it wrong, set once answered
+
+	Kind        kind          `json:"kind" bson:"kind,omitempty"`                     // empty is text
+	Bands       []*Band       `json:"bands,omitempty" bson:"bands,omitempty"`         // tolerance bands of scored kind
+	Rule        rule          `json:"rule,omitempty" bson:"rule,omitempty"`           // scoring rule of probability, empty is brier
+	Matcher     string        `json:"matcher,omitempty" bson:"matcher,omitempty"`     // matcher of free text answer, empty is exact
+	MaxEdits    int           `json:"max_edits,omitempty" bson:"max_edits,omitempty"` // tolerated edit distance of edit matcher
+	Options     []*Option     `json:"options,omitempty" bson:"options,omitempty"`     // empty is free text topic
+	Transitions []*Transition `json:"transitions" bson:"transitions,omitempty"`
+}
+
+// HasOption check whether option ID is declared by multiple-choice topic
+func (t *Topic) HasOption(id string) bool {
+	for _, opt := range t.Options {
+		if opt.ID == id {
+			return true
+		}
+	}
+
+	return false
 }
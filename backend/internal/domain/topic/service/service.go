@@ -0,0 +1,138 @@
+// Package service of topic, enforces topic state machine on top of basic service
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	typedRepo "github.com/di-collective/ditebak/backend/pkg/repo/typed"
+	"github.com/di-collective/ditebak/backend/pkg/service/basic"
+)
+
+// Repository of topic, state and answer are mutated atomically
+// so the update delegate only ever sees partial updates
+type Repository interface {
+	repo.Repository
+	repo.Mutator
+}
+
+// Service of topic
+type Service struct {
+	*basic.Service
+	rps Repository
+}
+
+// New topic service
+// @rps: persistence repository of topic
+func New(rps Repository) *Service {
+	return &Service{
+		Service: basic.New(rps),
+		rps:     rps,
+	}
+}
+
+// Topic of ID, missing topic is not found
+func (svc *Service) Topic(ctx context.Context, id string) (*dao.Topic, error) {
+	res, err := svc.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return typedRepo.Cast[*dao.Topic](res)
+}
+
+// Update a draft topic partially, published topic is only changed by transition and settlement
+// Verification:
+// 1. Topic must exists
+// 2. Topic must be draft
+// Then:
+// update the topic if it hasn't moved since it was verified,
+// unless a version is already expected by ctx, e.g. by If-Match
+func (svc *Service) Update(ctx context.Context, id string, obj interface{}) (interface{}, error) {
+	vctx, versions := repo.CollectVersions(ctx)
+	topic, err := svc.Topic(vctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if topic.State != dao.TopicStates.Draft() {
+		return nil, exception.New(http.StatusConflict, "Topic is already %s and can't be edited", topic.State)
+	}
+
+	if _, expected := repo.ExpectedVersion(ctx, id); !expected {
+		if version, ok := versions.Of(id); ok {
+			ctx = repo.ExpectVersion(ctx, id, version)
+		}
+	}
+
+	return svc.Service.Update(ctx, id, obj)
+}
+
+// Transition topic into another state
+// Verification:
+// 1. State must be known
+// 2. Topic must exists
+// 3. Transition from current state must be legal
+// Then:
+// record who and when the transition is made, topic moved meanwhile is a conflict
+func (svc *Service) Transition(ctx context.Context, id, to, by string) (*dao.Topic, error) {
+	next, ok := dao.TopicStates.Parse(to)
+	if !ok {
+		return nil, exception.New(http.StatusBadRequest, "Unknown topic state: %s", to)
+	}
+
+	topic, err := svc.Topic(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dao.CanTransition(topic.State, next) {
+		return nil, exception.New(http.StatusConflict, "Topic can't be moved from %s to %s", topic.State, next)
+	}
+
+	now := time.Now()
+	transitions := append(topic.Transitions, &dao.Transition{
+		From: topic.State,
+		To:   next,
+		By:   by,
+		At:   &now,
+	})
+
+	return svc.mutate(ctx, id, repo.Mutation{
+		If: map[string]interface{}{"state": string(topic.State)},
+		Set: map[string]interface{}{
+			"state":       string(next),
+			"transitions": transitions,
+			"updated_at":  now,
+		},
+	}, func() error {
+		return exception.New(http.StatusConflict, "Topic has been modified while moving to %s, please retry", next)
+	})
+}
+
+// Answer of topic and the settlement job settling it, unanswered topic is given empty answer and nil difficulty
+func (svc *Service) Answer(ctx context.Context, id, answer string, difficulty *int, settlement string) (*dao.Topic, error) {
+	return svc.mutate(ctx, id, repo.Mutation{
+		Set: map[string]interface{}{
+			"answer":     answer,
+			"difficulty": difficulty,
+			"settlement": settlement,
+			"updated_at": time.Now(),
+		},
+	}, nil)
+}
+
+// mutate topic and return it after mutation, unmatched mutation is the error of conflict
+func (svc *Service) mutate(ctx context.Context, id string, m repo.Mutation, conflict func() error) (*dao.Topic, error) {
+	res, err := svc.rps.Mutate(ctx, id, m)
+	if err == repo.ErrUnmatched && conflict != nil {
+		return nil, conflict()
+	} else if err != nil {
+		return nil, err
+	}
+
+	return typedRepo.Cast[*dao.Topic](res)
+}
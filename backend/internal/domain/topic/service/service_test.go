@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/memrepo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/repotest"
+)
+
+// edit of a draft, as the partial update given by REST
+type edit struct {
+	Question string `bson:"question,omitempty"`
+}
+
+// moving repository moves the topic meanwhile between the read and the update
+type moving struct {
+	Repository
+	meanwhile func(ctx context.Context, id string)
+}
+
+func (r *moving) Update(ctx context.Context, id string, obj interface{}) error {
+	if r.meanwhile != nil {
+		r.meanwhile(ctx, id)
+	}
+
+	return r.Repository.Update(ctx, id, obj)
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string // state of the topic, empty is missing
+		moveTo   string // state the topic is moved into meanwhile, empty is no race
+		wantCode int
+	}{
+		{"draft", "draft", "", 0},
+		{"published", "published", "", http.StatusConflict},
+		{"answered", "answered", "", http.StatusConflict},
+		{"missing", "", "", http.StatusNotFound},
+		{"published meanwhile", "draft", "published", http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			rps := memrepo.New(memrepo.NewStore(), "topics", nil, func() interface{} { return &dao.Topic{} }, &repotest.Delegate{})
+			id := primitive.NewObjectID()
+			if tt.state != "" {
+				state, _ := dao.TopicStates.Parse(tt.state)
+				if err := rps.Create(ctx, &dao.Topic{ID: id, Question: "before", State: state}); err != nil {
+					t.Fatalf("Create() error = %v", err)
+				}
+			}
+
+			mov := &moving{Repository: rps}
+			if tt.moveTo != "" {
+				mov.meanwhile = func(ctx context.Context, id string) {
+					if _, err := rps.Mutate(ctx, id, repo.Mutation{Set: map[string]interface{}{"state": tt.moveTo}}); err != nil {
+						t.Fatalf("Mutate() error = %v", err)
+					}
+				}
+			}
+
+			_, err := New(mov).Update(ctx, id.Hex(), &edit{Question: "after"})
+			want := "after"
+			if tt.wantCode != 0 {
+				exc, ok := exception.IsException(err)
+				if !ok || exc.Code() != tt.wantCode {
+					t.Fatalf("Update() error = %v, want code %d", err, tt.wantCode)
+				}
+				want = "before"
+			} else if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			res, err := rps.Get(ctx, id.Hex())
+			if tt.state == "" {
+				if err == nil {
+					t.Errorf("Get() of missing topic = %v, want it not upserted", res)
+				}
+				return
+			}
+			if got := res.(*dao.Topic).Question; got != want {
+				t.Errorf("question = %s, want %s", got, want)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ type providers interface {
 	Facebook() provider
 	Email() provider
 	Bot() provider
+	Parse(string) (provider, bool)
 }
 
 func (t *provider) Google() provider {
@@ -37,6 +38,48 @@ func (t *provider) Bot() provider {
 	return provider("bot")
 }
 
+// Parse string into known provider
+func (t *provider) Parse(s string) (provider, bool) {
+	switch p := provider(s); p {
+	case t.Google(), t.Facebook(), t.Email(), t.Bot():
+		return p, true
+	}
+
+	return provider(""), false
+}
+
+// Roles of user, stored as "role" custom claim of firebase session
+var (
+	roleEnum = role("")
+	Roles    = &roleEnum
+)
+
+type role string
+
+type roles interface {
+	Gambler() role
+	Moderator() role
+	Admin() role
+	Platform() role
+}
+
+func (t *role) Gambler() role {
+	return role("gambler")
+}
+
+func (t *role) Moderator() role {
+	return role("moderator")
+}
+
+func (t *role) Admin() role {
+	return role("admin")
+}
+
+// Platform is role of internal services, never assigned to a user
+func (t *role) Platform() role {
+	return role("platform")
+}
+
 // User database object
 type User struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
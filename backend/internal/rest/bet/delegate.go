@@ -10,33 +10,29 @@ import (
 
 type delegate struct{}
 
-func (del *delegate) Constructor() interface{} {
+func (del *delegate) Constructor() *dao.Bet {
 	return &dao.Bet{}
 }
 
-func (del *delegate) WillCreate(data interface{}) {
+func (del *delegate) WillCreate(bet *dao.Bet) {
 	now := time.Now()
-	bet := data.(*dao.Bet)
 	bet.CreatedAt = &now
 	bet.State = dao.BetStates.Placed()
 }
 
-func (del *delegate) DidCreate(created interface{}, id primitive.ObjectID) {
-	bet := created.(*dao.Bet)
+func (del *delegate) DidCreate(bet *dao.Bet, id primitive.ObjectID) {
 	bet.ID = id
 }
 
-func (del *delegate) WillUpdate(data interface{}, opt *options.UpdateOptions) {
+func (del *delegate) WillUpdate(bet *dao.Bet, opt *options.UpdateOptions) {
 	now := time.Now()
-	bet := data.(*dao.Bet)
 	bet.UpdatedAt = &now
 
 	opt.SetUpsert(true)
 }
 
-func (del *delegate) DidUpdate(data interface{}, upsert *primitive.ObjectID) {
+func (del *delegate) DidUpdate(bet *dao.Bet, upsert *primitive.ObjectID) {
 	if upsert != nil {
-		bet := data.(*dao.Bet)
 		bet.ID = *upsert
 	}
 }
@@ -2,36 +2,171 @@ package bet
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"os"
 	"reflect"
+	"strconv"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
+	"github.com/di-collective/ditebak/backend/internal/domain/bet/service"
+	userDao "github.com/di-collective/ditebak/backend/internal/domain/user/dao"
+	"github.com/di-collective/ditebak/backend/internal/rest/ledger"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
 	"github.com/di-collective/ditebak/backend/pkg/queryables"
+	"github.com/di-collective/ditebak/backend/pkg/repo/driver"
 	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+	typedRepo "github.com/di-collective/ditebak/backend/pkg/repo/typed"
 	"github.com/di-collective/ditebak/backend/pkg/rest"
-	"github.com/di-collective/ditebak/backend/pkg/service/basic"
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// restapi of bet, revise and cancel on top of basic REST
+type restapi struct {
+	rest.REST
+	svc  *service.Service
+	auth rest.Authenticator
+	rule *rest.Rule
+}
+
+// indexes of bet collection
+// one placed bet per topic and owner, cancelled bet is excluded so its owner can bet again
+// settled bets are excluded as well but no bet can be placed once topic is closed
+var indexes = []mongorepo.Index{
+	{
+		Name:    "topic_owner_placed",
+		Keys:    bson.D{{Key: "topic_id", Value: 1}, {Key: "owner", Value: 1}},
+		Unique:  true,
+		Partial: bson.M{"state": dao.BetStates.Placed()},
+	},
+	{Name: "owner_created_at", Keys: bson.D{{Key: "owner", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Name: "topic_state", Keys: bson.D{{Key: "topic_id", Value: 1}, {Key: "state", Value: 1}}},
+}
+
+// Revise command
+type Revise struct {
+	Prediction  string   `json:"prediction"`
+	Probability *float64 `json:"probability"`
+}
+
 // New instance of Bet REST API
-func New(coll *mongo.Collection) rest.REST {
+// stake is escrowed on create, reputation of owner can't fall below REPUTATION_FLOOR (default 0)
+// cancelled bet is refunded minus CANCELLATION_FEE percentage of its stake (default 0)
+// @coll, @users, @entries: mongo collections, must belong to the same client to share transaction
+// @auth: resolves identity of requester
+func New(coll, users, entries *mongo.Collection, auth rest.Authenticator) rest.REST {
 	delegate := &delegate{}
-	return rest.New(&rest.Config{
-		Resource: "bets",
-		Service: basic.New(mongorepo.New(
-			/* collection    */ coll,
-			/* default sort  */ map[string]int{"created_at": -1},
-			/* constructor   */ delegate.Constructor,
-			/* event handler */ delegate)),
-		CreatePayload: delegate.Constructor,
-		UpdatePayload: delegate.Constructor,
-		Convert:       nil, // dto == dao
-		Queryables: queryables.Collection{
-			{DtoKey: "topic", DaoKey: "topic_id", TypeOf: reflect.String},
-			{DtoKey: "owner", DaoKey: "owner", TypeOf: reflect.String},
-			{DtoKey: "state", DaoKey: "state", TypeOf: reflect.String},
-		},
-	})
+	admin, platform := string(userDao.Roles.Admin()), string(userDao.Roles.Platform())
+	floor, _ := strconv.ParseInt(os.Getenv("REPUTATION_FLOOR"), 10, 64)
+	fee, _ := strconv.Atoi(os.Getenv("CANCELLATION_FEE"))
+	if fee < 0 || fee > 100 {
+		log.Warnf("CANCELLATION_FEE must be a percentage, got %d, no fee is charged", fee)
+		fee = 0
+	}
+	rps := NewRepo(coll)
+	if _, err := rps.SyncIndexes(context.Background()); err != nil {
+		log.Errorln("Failed to sync indexes of bets:", err)
+	}
+
+	svc := service.New(
+		/* transactor */ rps,
+		/* bets       */ typedRepo.Of[*dao.Bet, *dao.Bet](rps),
+		/* mutator    */ rps,
+		/* ledger     */ ledger.NewService(entries, users),
+		/* floor      */ floor,
+		/* fee        */ fee)
+
+	return &restapi{
+		svc:  svc,
+		auth: auth,
+		rule: rest.Roles(platform, admin),
+		REST: rest.NewTyped(&rest.Typed[*dao.Bet, *dao.Bet]{
+			Resource:      "bets",
+			Service:       svc,
+			CreatePayload: delegate.Constructor,
+			UpdatePayload: delegate.Constructor, // dto == dao
+			Queryables: queryables.Collection{
+				{DtoKey: "topic", DaoKey: "topic_id", TypeOf: reflect.String},
+				{DtoKey: "owner", DaoKey: "owner", TypeOf: reflect.String},
+				{DtoKey: "state", DaoKey: "state", TypeOf: reflect.String},
+			},
+			Auth: auth,
+			Policy: rest.Policy{
+				rest.VerbFind:   rest.Roles(platform, admin), // gambler reads own bets through /ggw
+				rest.VerbGet:    rest.Roles(platform, admin),
+				rest.VerbCreate: rest.Roles(platform, admin),
+				rest.VerbUpdate: rest.Roles(platform, admin),
+				rest.VerbDelete: rest.Roles(admin),
+				rest.VerbRemove: rest.Roles(admin),
+			},
+		}),
+	}
+}
+
+// WithRouter initialize routes using julienschmidth httprouter
+func (api *restapi) WithRouter(router *httprouter.Router) {
+	api.REST.WithRouter(router)
+	router.POST("/bets/:id/revisions", rest.Authorize(api.auth, api.rule, api.Revise))
+	router.POST("/bets/:id/cancellation", rest.Authorize(api.auth, api.rule, api.Cancel))
+}
+
+// Revise prediction of a placed bet
+func (api *restapi) Revise(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	res := rest.NewAPIResponse(w, r)
+
+	cmd := &Revise{}
+	if err := rest.ParseBody(r, cmd); err != nil {
+		res.Error("Failed to parse payload", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	bet, err := api.svc.Revise(r.Context(), id, cmd.Prediction, cmd.Probability)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error(fmt.Sprintf("Failed to revise bet with id: %s", id), err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(bet).Respond(http.StatusOK)
+}
+
+// Cancel a placed bet
+func (api *restapi) Cancel(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	res := rest.NewAPIResponse(w, r)
+
+	bet, err := api.svc.Cancel(r.Context(), id)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error(fmt.Sprintf("Failed to cancel bet with id: %s", id), err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(bet).Respond(http.StatusOK)
+}
+
+// NewRepo of bet, shared with other resources operating on bets
+func NewRepo(coll *mongo.Collection) driver.Repo {
+	delegate := typedRepo.Delegate[*dao.Bet, *dao.Bet](&delegate{})
+	return driver.New(
+		/* collection    */ coll,
+		/* default sort  */ map[string]int{"created_at": -1},
+		/* constructor   */ delegate.Constructor,
+		/* event handler */ delegate,
+		/* indexes       */ indexes...)
 }
 
 // Teardown REST API
@@ -8,26 +8,24 @@ import (
 
 type delegate struct{}
 
-func (del *delegate) Constructor() interface{} {
+func (del *delegate) Constructor() *dao.Credential {
 	return &dao.Credential{}
 }
 
-func (del *delegate) WillCreate(data interface{}) {
+func (del *delegate) WillCreate(cred *dao.Credential) {
 	// do nothing
 }
 
-func (del *delegate) DidCreate(created interface{}, id primitive.ObjectID) {
-	cred := created.(*dao.Credential)
+func (del *delegate) DidCreate(cred *dao.Credential, id primitive.ObjectID) {
 	cred.ID = id
 }
 
-func (del *delegate) WillUpdate(data interface{}, opt *options.UpdateOptions) {
+func (del *delegate) WillUpdate(cred *dao.Credential, opt *options.UpdateOptions) {
 	opt.SetUpsert(true)
 }
 
-func (del *delegate) DidUpdate(data interface{}, upsert *primitive.ObjectID) {
+func (del *delegate) DidUpdate(cred *dao.Credential, upsert *primitive.ObjectID) {
 	if upsert != nil {
-		cred := data.(*dao.Credential)
 		cred.ID = *upsert
 	}
 }
@@ -6,32 +6,65 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/di-collective/ditebak/backend/internal/domain/credential/dao"
+	userDao "github.com/di-collective/ditebak/backend/internal/domain/user/dao"
 	"github.com/di-collective/ditebak/backend/pkg/queryables"
+	"github.com/di-collective/ditebak/backend/pkg/repo/driver"
 	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+	typedRepo "github.com/di-collective/ditebak/backend/pkg/repo/typed"
 	"github.com/di-collective/ditebak/backend/pkg/rest"
-	"github.com/di-collective/ditebak/backend/pkg/service/basic"
+	typedService "github.com/di-collective/ditebak/backend/pkg/service/typed"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// indexes of credential collection, one credential per email
+var indexes = []mongorepo.Index{
+	{Name: "email", Keys: bson.D{{Key: "email", Value: 1}}, Unique: true},
+}
+
 // New instance of Credential REST API
-func New(coll *mongo.Collection) rest.REST {
+// @coll: mongo collection
+// @auth: resolves identity of requester
+func New(coll *mongo.Collection, auth rest.Authenticator) rest.REST {
 	delegate := &delegate{}
-	return rest.New(&rest.Config{
-		Resource: "credentials",
-		Service: basic.New(mongorepo.New(
-			/* collection   */ coll,
-			/* default sort */ map[string]int{"email": 1},
-			/* constructor  */ delegate.Constructor,
-			/* id assigner  */ delegate)),
+	admin, platform := string(userDao.Roles.Admin()), string(userDao.Roles.Platform())
+	rps := NewRepo(coll)
+	if _, err := rps.SyncIndexes(context.Background()); err != nil {
+		log.Errorln("Failed to sync indexes of credentials:", err)
+	}
+
+	return rest.NewTyped(&rest.Typed[*dao.Credential, *dao.Credential]{
+		Resource:      "credentials",
+		Service:       typedService.Basic(typedRepo.Of[*dao.Credential, *dao.Credential](rps)),
 		CreatePayload: delegate.Constructor,
-		UpdatePayload: delegate.Constructor,
-		Convert:       nil, // dto == dao
+		UpdatePayload: delegate.Constructor, // dto == dao
 		Queryables: queryables.Collection{
 			{DtoKey: "email", DaoKey: "email", TypeOf: reflect.String},
 		},
+		Auth: auth,
+		Policy: rest.Policy{
+			rest.VerbFind:   rest.Roles(platform, admin),
+			rest.VerbGet:    rest.Roles(platform, admin),
+			rest.VerbCreate: rest.Roles(platform, admin),
+			rest.VerbUpdate: rest.Roles(platform, admin),
+			rest.VerbDelete: rest.Roles(admin),
+			rest.VerbRemove: rest.Roles(admin),
+		},
 	})
 }
 
+// NewRepo of credential
+func NewRepo(coll *mongo.Collection) driver.Repo {
+	delegate := typedRepo.Delegate[*dao.Credential, *dao.Credential](&delegate{})
+	return driver.New(
+		/* collection   */ coll,
+		/* default sort */ map[string]int{"email": 1},
+		/* constructor  */ delegate.Constructor,
+		/* id assigner  */ delegate,
+		/* indexes      */ indexes...)
+}
+
 // Teardown REST API
 func Teardown(ctx context.Context) error {
 	// TODO: graceful shutdown
@@ -9,10 +9,11 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 
-	firebase "firebase.google.com/go"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/di-collective/ditebak/backend/internal/rest/session"
 	"github.com/di-collective/ditebak/backend/internal/usecase/gambler"
 	"github.com/di-collective/ditebak/backend/internal/usecase/gambler/command"
 	"github.com/di-collective/ditebak/backend/internal/usecase/gambler/dto"
@@ -27,28 +28,32 @@ import (
 type restapi struct {
 	conf *gambler.Config
 	ggw  *gambler.Gateway
+	auth rest.Authenticator
 }
 
 // New gambler micro API gateway
 func New() rest.REST {
-	ctx := context.Background()
-	fap, _ := firebase.NewApp(ctx, nil)
-	fac, _ := fap.Auth(ctx)
+	fac := session.NewAuth(context.Background())
 
+	floor, _ := strconv.ParseInt(os.Getenv("REPUTATION_FLOOR"), 10, 64)
 	conf := &gambler.Config{
 		Const: &gambler.ConfigConst{
-			MaxStake:   10,
-			AuthClient: fac,
+			MaxStake:        10,
+			ReputationFloor: floor,
+			AuthClient:      fac,
+			ServiceKey:      os.Getenv("SERVICE_KEY"),
 		},
 		URL: &gambler.ConfigURL{
-			User:  defaultOnEmptyEnv(os.Getenv("URL_USER"), "http://localhost:8080/users"),
-			Topic: defaultOnEmptyEnv(os.Getenv("URL_TOPIC"), "http://localhost:8080/topics"),
-			Bet:   defaultOnEmptyEnv(os.Getenv("URL_BET"), "http://localhost:8080/bets"),
+			User:   defaultOnEmptyEnv(os.Getenv("URL_USER"), "http://localhost:8080/users"),
+			Topic:  defaultOnEmptyEnv(os.Getenv("URL_TOPIC"), "http://localhost:8080/topics"),
+			Bet:    defaultOnEmptyEnv(os.Getenv("URL_BET"), "http://localhost:8080/bets"),
+			Ledger: defaultOnEmptyEnv("URL_LEDGER", "http://localhost:8080/ledger"),
 		},
 	}
 	api := &restapi{
 		conf: conf,
 		ggw:  gambler.New(conf),
+		auth: session.New(fac, conf.Const.ServiceKey),
 	}
 
 	return api
@@ -57,39 +62,20 @@ func New() rest.REST {
 // WithRouter initialize routes using julienschmidth httprouter
 func (api *restapi) WithRouter(router *httprouter.Router) {
 	router.Handle("GET", "/ggw/profile", api.guard(api.MyProfile))
+	router.Handle("GET", "/ggw/profile/ledger", api.guard(api.MyLedger)) // history of reputation
 
 	router.Handle("GET", "/ggw/topics", api.TopicList)                    // list of topics
 	router.Handle("GET", "/ggw/topics/:id", api.Topic)                    // one topic
 	router.Handle("GET", "/ggw/topics/:id/bet/", api.guard(api.TopicBet)) // list of bet on a topic but only expects 1
 
-	router.Handle("GET", "/ggw/bets", api.guard(api.MyBets))    // list of bets
-	router.Handle("POST", "/ggw/bets", api.guard(api.PlaceBet)) // place a bet
+	router.Handle("GET", "/ggw/bets", api.guard(api.MyBets))           // list of bets
+	router.Handle("POST", "/ggw/bets", api.guard(api.PlaceBet))        // place a bet
+	router.Handle("PATCH", "/ggw/bets/:id", api.guard(api.ChangeBet))  // change prediction before closing
+	router.Handle("DELETE", "/ggw/bets/:id", api.guard(api.CancelBet)) // cancel before closing
 }
 
 func (api *restapi) guard(next httprouter.Handle) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		ctx := r.Context()
-		res := rest.NewAPIResponse(w, r)
-		session, err := r.Cookie("fa-session")
-		if err != nil || session == nil || session.Value == "" {
-			res.Error("You are not authenticated", err).Respond(http.StatusUnauthorized)
-			return
-		}
-
-		jwtok, err := api.conf.Const.AuthClient.VerifySessionCookie(ctx, session.Value)
-		if err != nil {
-			res.Error("You are not authenticated", err).Respond(http.StatusUnauthorized)
-			return
-		}
-
-		log.Tracef("TOKEN: %+v\n", jwtok)
-		log.Tracef("CLAIMS: %+v\n", jwtok.Claims)
-
-		email, _ := jwtok.Claims["email"]
-		ctx = context.WithValue(ctx, global.Context.Email(), email)
-		r = r.WithContext(ctx)
-		next(w, r, p)
-	}
+	return rest.Authorize(api.auth, rest.Authenticated(), next)
 }
 
 // MyProfile ...
@@ -112,6 +98,27 @@ func (api *restapi) MyProfile(w http.ResponseWriter, r *http.Request, p httprout
 	res.Payload(user).Respond(http.StatusOK)
 }
 
+// MyLedger ...
+func (api *restapi) MyLedger(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	ctx := r.Context()
+	res := rest.NewAPIResponse(w, r)
+	email := ctx.Value(global.Context.Email()).(string)
+
+	result, err := api.ggw.MyLedger(ctx, email, r.URL.Query())
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed get your reputation history", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(result)
+}
+
 // TopicList find all topic where
 // @state != draft
 func (api *restapi) TopicList(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
@@ -217,6 +224,51 @@ func (api *restapi) PlaceBet(w http.ResponseWriter, r *http.Request, p httproute
 	res.Payload(bet).Respond(http.StatusCreated)
 }
 
+// ChangeBet prediction before the topic closes
+func (api *restapi) ChangeBet(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	cbt := &command.ChangeBet{}
+	if err := defaultRequestUnwrapper(cbt)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	email := ctx.Value(global.Context.Email()).(string)
+	bet, err := api.ggw.ChangeBet(ctx, email, p.ByName("id"), cbt)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to change a bet", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(bet).Respond(http.StatusOK)
+}
+
+// CancelBet before the topic closes
+func (api *restapi) CancelBet(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+
+	email := ctx.Value(global.Context.Email()).(string)
+	bet, err := api.ggw.CancelBet(ctx, email, p.ByName("id"))
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to cancel a bet", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(bet).Respond(http.StatusOK)
+}
+
 // @obj: please send a pointer to a struct
 func defaultRequestUnwrapper(obj interface{}) func(body io.ReadCloser) error {
 	return func(body io.ReadCloser) error {
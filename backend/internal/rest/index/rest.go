@@ -0,0 +1,117 @@
+package index
+
+import (
+	"context"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	userDao "github.com/di-collective/ditebak/backend/internal/domain/user/dao"
+	"github.com/di-collective/ditebak/backend/internal/rest/bet"
+	"github.com/di-collective/ditebak/backend/internal/rest/credential"
+	"github.com/di-collective/ditebak/backend/internal/rest/ledger"
+	"github.com/di-collective/ditebak/backend/internal/rest/settlement"
+	"github.com/di-collective/ditebak/backend/internal/rest/topic"
+	"github.com/di-collective/ditebak/backend/internal/rest/user"
+	"github.com/di-collective/ditebak/backend/pkg/repo/driver"
+	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+	"github.com/di-collective/ditebak/backend/pkg/rest"
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// restapi of index management, declared indexes of every collection against actual ones
+type restapi struct {
+	repos []driver.Repo
+	auth  rest.Authenticator
+}
+
+// Dropped indexes of a collection
+type Dropped struct {
+	Collection string   `json:"collection"`
+	Indexes    []string `json:"indexes"`
+}
+
+// New instance of Index REST API
+// @users, @credentials, @topics, @bets, @entries, @jobs: mongo collections
+// @auth: resolves identity of requester
+func New(users, credentials, topics, bets, entries, jobs *mongo.Collection, auth rest.Authenticator) rest.REST {
+	return &restapi{
+		repos: []driver.Repo{
+			user.NewRepo(users),
+			credential.NewRepo(credentials),
+			topic.NewRepo(topics),
+			bet.NewRepo(bets),
+			ledger.NewRepo(entries),
+			settlement.NewRepo(jobs),
+		},
+		auth: auth,
+	}
+}
+
+// WithRouter initialize routes using julienschmidth httprouter
+func (api *restapi) WithRouter(router *httprouter.Router) {
+	rule := rest.Roles(string(userDao.Roles.Admin()))
+	router.GET("/indexes", rest.Authorize(api.auth, rule, api.Inspect))
+	router.POST("/indexes/sync", rest.Authorize(api.auth, rule, api.Sync))
+	router.DELETE("/indexes/unmanaged", rest.Authorize(api.auth, rule, api.DropUnmanaged))
+}
+
+// Inspect indexes of every collection, reports missing, drifted and unmanaged ones
+func (api *restapi) Inspect(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+
+	reports := make([]*mongorepo.IndexReport, 0, len(api.repos))
+	for _, rps := range api.repos {
+		report, err := rps.InspectIndexes(r.Context())
+		if err != nil {
+			res.Error("Failed to inspect indexes", err).Respond(http.StatusInternalServerError)
+			return
+		}
+		reports = append(reports, report)
+	}
+
+	res.Payload(reports).Respond(http.StatusOK)
+}
+
+// Sync indexes of every collection, creates missing ones
+func (api *restapi) Sync(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+
+	reports := make([]*mongorepo.IndexReport, 0, len(api.repos))
+	for _, rps := range api.repos {
+		report, err := rps.SyncIndexes(r.Context())
+		if err != nil {
+			res.Error("Failed to sync indexes", err).Respond(http.StatusInternalServerError)
+			return
+		}
+		reports = append(reports, report)
+	}
+
+	res.Payload(reports).Respond(http.StatusOK)
+}
+
+// DropUnmanaged indexes of every collection, responds with dropped indexes
+func (api *restapi) DropUnmanaged(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+
+	dropped := make([]*Dropped, 0, len(api.repos))
+	for _, rps := range api.repos {
+		names, err := rps.DropUnmanaged(r.Context())
+		if err != nil {
+			res.Error("Failed to drop unmanaged indexes", err).Respond(http.StatusInternalServerError)
+			return
+		}
+		log.Warnf("Unmanaged indexes of %s are dropped: %v", rps.Name(), names)
+		dropped = append(dropped, &Dropped{Collection: rps.Name(), Indexes: names})
+	}
+
+	res.Payload(dropped).Respond(http.StatusOK)
+}
+
+// Teardown REST API
+func Teardown(ctx context.Context) error {
+	// TODO: graceful shutdown
+	log.Info("SHUTTING DOWN")
+	return nil
+}
@@ -0,0 +1,34 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/di-collective/ditebak/backend/internal/domain/ledger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type delegate struct{}
+
+func (del *delegate) Constructor() interface{} {
+	return &ledger.Entry{}
+}
+
+func (del *delegate) WillCreate(data interface{}) {
+	now := time.Now()
+	entry := data.(*ledger.Entry)
+	entry.CreatedAt = &now
+}
+
+func (del *delegate) DidCreate(created interface{}, id primitive.ObjectID) {
+	entry := created.(*ledger.Entry)
+	entry.ID = id
+}
+
+func (del *delegate) WillUpdate(data interface{}, opt *options.UpdateOptions) {
+	// do nothing, entry is never updated
+}
+
+func (del *delegate) DidUpdate(data interface{}, upsert *primitive.ObjectID) {
+	// do nothing, entry is never updated
+}
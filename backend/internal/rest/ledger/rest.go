@@ -0,0 +1,148 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/di-collective/ditebak/backend/internal/domain/ledger"
+	userDao "github.com/di-collective/ditebak/backend/internal/domain/user/dao"
+	"github.com/di-collective/ditebak/backend/internal/rest/user"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/queryables"
+	"github.com/di-collective/ditebak/backend/pkg/repo/driver"
+	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+	"github.com/di-collective/ditebak/backend/pkg/rest"
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// restapi of ledger, find, post and reconcile entries
+type restapi struct {
+	rest.REST
+	svc  *ledger.Service
+	auth rest.Authenticator
+	rule *rest.Rule
+}
+
+// indexes of ledger entry collection
+var indexes = []mongorepo.Index{
+	{Name: "user_created_at", Keys: bson.D{{Key: "user", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Name: "bet", Keys: bson.D{{Key: "bet", Value: 1}}},
+	{Name: "settlement", Keys: bson.D{{Key: "settlement", Value: 1}}},
+	{Name: "user_opening", Keys: bson.D{{Key: "user", Value: 1}}, Unique: true, Partial: bson.M{"reason": "opening"}},
+	{Name: "user_grant", Keys: bson.D{{Key: "user", Value: 1}}, Unique: true, Partial: bson.M{"reason": "grant"}},
+}
+
+// Reconcile command
+type Reconcile struct {
+	User string `json:"user"`
+}
+
+// New instance of Ledger REST API
+// entries are append-only, only grant and adjustment can be posted from API
+// @entries, @users: mongo collections, must belong to the same client to share transaction
+// @auth: resolves identity of requester
+func New(entries, users *mongo.Collection, auth rest.Authenticator) rest.REST {
+	delegate := &delegate{}
+	admin, platform := string(userDao.Roles.Admin()), string(userDao.Roles.Platform())
+
+	svc := NewService(entries, users)
+	if _, err := NewRepo(entries).SyncIndexes(context.Background()); err != nil {
+		log.Errorln("Failed to sync indexes of ledger:", err)
+	}
+
+	// reputation earned before the ledger is opened before any posting is served
+	if opened, err := svc.Open(context.Background()); err != nil {
+		log.Errorln("Failed to open ledger of existing users:", err)
+	} else if opened > 0 {
+		log.Infof("Opened ledger of %d existing users", opened)
+	}
+
+	return &restapi{
+		svc:  svc,
+		auth: auth,
+		rule: rest.Roles(platform, admin),
+		REST: rest.New(&rest.Config{
+			Resource:      "ledger",
+			Service:       svc,
+			CreatePayload: delegate.Constructor,
+			UpdatePayload: delegate.Constructor,
+			Convert:       nil, // dto == dao
+			Queryables: queryables.Collection{
+				{DtoKey: "user", DaoKey: "user", TypeOf: reflect.String},
+				{DtoKey: "topic", DaoKey: "topic", TypeOf: reflect.String},
+				{DtoKey: "bet", DaoKey: "bet", TypeOf: reflect.String},
+				{DtoKey: "reason", DaoKey: "reason", TypeOf: reflect.String},
+			},
+			Auth: auth,
+			Policy: rest.Policy{
+				rest.VerbFind:   rest.Roles(platform, admin),
+				rest.VerbGet:    rest.Roles(platform, admin),
+				rest.VerbCreate: rest.Roles(platform, admin),
+			},
+		}),
+	}
+}
+
+// WithRouter initialize routes using julienschmidth httprouter
+func (api *restapi) WithRouter(router *httprouter.Router) {
+	api.REST.WithRouter(router)
+	router.POST("/ledger/reconciliations", rest.Authorize(api.auth, api.rule, api.Reconcile))
+}
+
+// Reconcile reputation of a user against the ledger
+func (api *restapi) Reconcile(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+
+	cmd := &Reconcile{}
+	if err := rest.ParseBody(r, cmd); err != nil {
+		res.Error("Failed to parse payload", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	result, err := api.svc.Reconcile(r.Context(), cmd.User)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error(fmt.Sprintf("Failed to reconcile reputation of user: %s", cmd.User), err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(result).Respond(http.StatusOK)
+}
+
+// NewRepo of ledger entry
+func NewRepo(coll *mongo.Collection) driver.Repo {
+	delegate := &delegate{}
+	return driver.New(
+		/* collection    */ coll,
+		/* default sort  */ map[string]int{"created_at": -1},
+		/* constructor   */ delegate.Constructor,
+		/* event handler */ delegate,
+		/* indexes       */ indexes...)
+}
+
+// NewService of ledger, shared with other resources changing reputation
+// @entries, @users: mongo collections, must belong to the same client to share transaction
+func NewService(entries, users *mongo.Collection) *ledger.Service {
+	userRepo := user.NewRepo(users)
+	return ledger.New(
+		/* transactor */ userRepo,
+		/* entries    */ NewRepo(entries),
+		/* users      */ userRepo)
+}
+
+// Teardown REST API
+func Teardown(ctx context.Context) error {
+	// TODO: graceful shutdown
+	log.Info("SHUTTING DOWN")
+	return nil
+}
@@ -0,0 +1,362 @@
+package moderator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+
+	userDao "github.com/di-collective/ditebak/backend/internal/domain/user/dao"
+	"github.com/di-collective/ditebak/backend/internal/rest/session"
+	"github.com/di-collective/ditebak/backend/internal/usecase/moderator"
+	"github.com/di-collective/ditebak/backend/internal/usecase/moderator/command"
+	"github.com/di-collective/ditebak/backend/internal/usecase/moderator/dto"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/global"
+	"github.com/di-collective/ditebak/backend/pkg/rest"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// rest API for moderator
+type restapi struct {
+	conf *moderator.Config
+	mgw  *moderator.Gateway
+	auth rest.Authenticator
+}
+
+// New moderator micro API gateway
+func New() rest.REST {
+	fac := session.NewAuth(context.Background())
+
+	conf := &moderator.Config{
+		Const: &moderator.ConfigConst{
+			ServiceKey: os.Getenv("SERVICE_KEY"),
+		},
+		URL: &moderator.ConfigURL{
+			Topic:      defaultOnEmptyEnv("URL_TOPIC", "http://localhost:8080/topics"),
+			Answer:     defaultOnEmptyEnv("URL_ANSWER", "http://localhost:8080/pgw/answers"),
+			Correction: defaultOnEmptyEnv("URL_CORRECTION", "http://localhost:8080/pgw/corrections"),
+			Void:       defaultOnEmptyEnv("URL_VOID", "http://localhost:8080/pgw/voids"),
+			DryRun:     defaultOnEmptyEnv("URL_DRY_RUN", "http://localhost:8080/pgw/matchers/dry-run"),
+			Preview:    defaultOnEmptyEnv("URL_MGW_PREVIEW", "http://localhost:8080/pgw/previews"),
+		},
+	}
+	api := &restapi{
+		conf: conf,
+		mgw:  moderator.New(conf),
+		auth: session.New(fac, conf.Const.ServiceKey),
+	}
+
+	return api
+}
+
+// WithRouter initialize routes using julienschmidth httprouter
+func (api *restapi) WithRouter(router *httprouter.Router) {
+	router.Handle("GET", "/mgw/topics", api.guard(api.TopicList))   // list of topics in any state
+	router.Handle("GET", "/mgw/topics/:id", api.guard(api.Topic))   // one topic
+	router.Handle("POST", "/mgw/topics", api.guard(api.DraftTopic)) // draft a new topic
+	router.Handle("PATCH", "/mgw/topics/:id", api.guard(api.EditTopic))
+
+	router.Handle("POST", "/mgw/topics/:id/publish", api.guard(api.PublishTopic))
+	router.Handle("POST", "/mgw/topics/:id/close", api.guard(api.CloseTopic))
+	router.Handle("POST", "/mgw/topics/:id/answer", api.guard(api.AnswerTopic))
+	router.Handle("POST", "/mgw/topics/:id/preview", api.guard(api.PreviewAnswer))
+	router.Handle("POST", "/mgw/topics/:id/correct", api.guard(api.CorrectTopic))
+	router.Handle("POST", "/mgw/topics/:id/void", api.guard(api.VoidTopic))
+	router.Handle("POST", "/mgw/topics/:id/dry-run", api.guard(api.DryRunTopic))
+	router.Handle("GET", "/mgw/answers/:job", api.guard(api.AnswerJob)) // progress of answering
+}
+
+// guard moderator operations, only moderators are allowed
+func (api *restapi) guard(next httprouter.Handle) httprouter.Handle {
+	return rest.Authorize(api.auth, rest.Roles(
+		string(userDao.Roles.Moderator()),
+		string(userDao.Roles.Admin()),
+	), next)
+}
+
+// TopicList find all topic regardless of its state
+func (api *restapi) TopicList(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+
+	topicURL, _ := url.Parse(api.conf.URL.Topic)
+	topicURL.RawQuery = r.URL.Query().Encode()
+	result, etag, err := api.mgw.Forward(ctx, topicURL.String())
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed get topics", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	setETag(w, etag)
+	w.WriteHeader(http.StatusOK)
+	w.Write(result)
+}
+
+// Topic one
+func (api *restapi) Topic(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+
+	result, etag, err := api.mgw.Forward(ctx, api.conf.GetTopicURL(p.ByName("id")))
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed get topic", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	setETag(w, etag) // to be sent back as If-Match of EditTopic
+	w.WriteHeader(http.StatusOK)
+	w.Write(result)
+}
+
+// DraftTopic ...
+func (api *restapi) DraftTopic(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	dft := &command.DraftTopic{}
+	if err := defaultRequestUnwrapper(dft)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	// optimistic coding ...
+	// .DraftTopic is guarded endpoint so email should already put by middleware
+	email := ctx.Value(global.Context.Email()).(string)
+
+	topic, err := api.mgw.DraftTopic(ctx, email, dft)
+	api.respondTopic(res, topic, err, http.StatusCreated, "Failed to draft a topic")
+}
+
+// EditTopic ...
+func (api *restapi) EditTopic(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	edt := &command.EditTopic{}
+	if err := defaultRequestUnwrapper(edt)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	topic, etag, err := api.mgw.EditTopic(ctx, p.ByName("id"), r.Header.Get("If-Match"), edt)
+	if err == nil {
+		setETag(w, etag)
+	}
+	api.respondTopic(res, topic, err, http.StatusOK, "Failed to edit a topic")
+}
+
+// PublishTopic ...
+func (api *restapi) PublishTopic(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	topic, err := api.mgw.PublishTopic(r.Context(), p.ByName("id"))
+	api.respondTopic(res, topic, err, http.StatusOK, "Failed to publish a topic")
+}
+
+// CloseTopic ...
+func (api *restapi) CloseTopic(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	topic, err := api.mgw.CloseTopic(r.Context(), p.ByName("id"))
+	api.respondTopic(res, topic, err, http.StatusOK, "Failed to close a topic")
+}
+
+// AnswerTopic ...
+func (api *restapi) AnswerTopic(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	ans := &command.Answer{}
+	if err := defaultRequestUnwrapper(ans)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+	ans.Topic = p.ByName("id")
+
+	job, err := api.mgw.AnswerTopic(ctx, ans)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to answer a topic", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(job).Respond(http.StatusAccepted)
+}
+
+// PreviewAnswer ...
+func (api *restapi) PreviewAnswer(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	ans := &command.Answer{}
+	if err := defaultRequestUnwrapper(ans)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+	ans.Topic = p.ByName("id")
+
+	preview, err := api.mgw.PreviewAnswer(ctx, ans)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to preview answer of a topic", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(preview).Respond(http.StatusOK)
+}
+
+// CorrectTopic ...
+func (api *restapi) CorrectTopic(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	cor := &command.Correct{}
+	if err := defaultRequestUnwrapper(cor)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+	cor.Topic = p.ByName("id")
+
+	job, err := api.mgw.CorrectTopic(ctx, cor)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to correct a topic", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(job).Respond(http.StatusAccepted)
+}
+
+// DryRunTopic ...
+func (api *restapi) DryRunTopic(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	ans := &command.Answer{}
+	if err := defaultRequestUnwrapper(ans)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+	ans.Topic = p.ByName("id")
+
+	result, err := api.mgw.DryRunTopic(ctx, ans)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to dry run matchers", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(result).Respond(http.StatusOK)
+}
+
+// VoidTopic ...
+func (api *restapi) VoidTopic(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	vd := &command.Void{}
+	if err := defaultRequestUnwrapper(vd)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+	vd.Topic = p.ByName("id")
+
+	job, err := api.mgw.VoidTopic(ctx, vd)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to void a topic", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(job).Respond(http.StatusAccepted)
+}
+
+// AnswerJob progress of answering a topic
+func (api *restapi) AnswerJob(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+
+	result, _, err := api.mgw.Forward(ctx, api.conf.GetAnswerURL(p.ByName("job")))
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed get answering progress", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(result)
+}
+
+func (api *restapi) respondTopic(res *rest.APIResponse, topic *dto.Topic, err error, code int, failure string) {
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error(failure, err).Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(topic).Respond(code)
+}
+
+// setETag of the forwarded resource, if any
+func setETag(w http.ResponseWriter, etag string) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+}
+
+// @obj: please send a pointer to a struct
+func defaultRequestUnwrapper(obj interface{}) func(body io.ReadCloser) error {
+	return func(body io.ReadCloser) error {
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+
+		wrapper := &dto.Wrapper{
+			Data: obj,
+		}
+		return json.Unmarshal(data, wrapper)
+	}
+}
+
+func defaultOnEmptyEnv(env, def string) string {
+	obj := os.Getenv(env)
+	if obj == "" {
+		obj = def
+	}
+
+	return obj
+}
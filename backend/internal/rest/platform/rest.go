@@ -8,10 +8,11 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
-	firebase "firebase.google.com/go"
-
+	userDao "github.com/di-collective/ditebak/backend/internal/domain/user/dao"
+	"github.com/di-collective/ditebak/backend/internal/rest/session"
 	"github.com/di-collective/ditebak/backend/internal/usecase/platform"
 	"github.com/di-collective/ditebak/backend/internal/usecase/platform/command"
 	"github.com/di-collective/ditebak/backend/internal/usecase/platform/dto"
@@ -25,18 +26,24 @@ import (
 type restapi struct {
 	conf *platform.Config
 	pgw  *platform.Gateway
+	auth rest.Authenticator
 }
 
 // New platform micro API gateway
 func New() rest.REST {
-	ctx := context.Background()
-	fap, _ := firebase.NewApp(ctx, nil)
-	fac, _ := fap.Auth(ctx)
+	fac := session.NewAuth(context.Background())
+
+	grant, err := strconv.ParseInt(os.Getenv("STARTING_GRANT"), 10, 64)
+	if err != nil {
+		grant = 100
+	}
 
 	conf := &platform.Config{
 		Const: &platform.ConfigConst{
 			SessionDuration: 5 * 24 * time.Hour,
 			AuthClient:      fac,
+			ServiceKey:      os.Getenv("SERVICE_KEY"),
+			StartingGrant:   grant,
 			Prod:            os.Getenv("PROD") == "true",
 		},
 		URL: &platform.ConfigURL{
@@ -44,11 +51,17 @@ func New() rest.REST {
 			User:  defaultOnEmptyEnv(os.Getenv("URL_USER"), "http://localhost:8080/users"),
 			Topic: defaultOnEmptyEnv(os.Getenv("URL_TOPIC"), "http://localhost:8080/topics"),
 			Bet:   defaultOnEmptyEnv(os.Getenv("URL_BET"), "http://localhost:8080/bets"),
+
+			Settlement: defaultOnEmptyEnv("URL_SETTLEMENT", "http://localhost:8080/settlements"),
+			Ledger:     defaultOnEmptyEnv("URL_LEDGER", "http://localhost:8080/ledger"),
+			Matcher:    defaultOnEmptyEnv("URL_MATCHER", "http://localhost:8080/matchers"),
+			Preview:    defaultOnEmptyEnv("URL_SETTLEMENT_PREVIEW", "http://localhost:8080/previews"),
 		},
 	}
 	api := &restapi{
 		conf: conf,
 		pgw:  platform.New(conf),
+		auth: session.New(fac, conf.Const.ServiceKey),
 	}
 
 	return api
@@ -59,7 +72,22 @@ func (api *restapi) WithRouter(router *httprouter.Router) {
 	router.Handle("POST", "/pgw/login", api.Login)
 	router.Handle("GET", "/pgw/logout", api.Logout)
 
-	router.Handle("POST", "/pgw/answers", api.Answer) // answer a topic
+	router.Handle("POST", "/pgw/answers", api.guard(api.Answer))                  // answer a topic
+	router.Handle("POST", "/pgw/previews", api.guard(api.PreviewAnswer))          // preview answering a topic
+	router.Handle("GET", "/pgw/answers/:job", api.guard(api.AnswerJob))           // progress of answering
+	router.Handle("POST", "/pgw/answers/:job/retry", api.guard(api.RetryAnswer))  // retry failed answering
+	router.Handle("POST", "/pgw/corrections", api.guard(api.Correct))             // correct answer of a topic
+	router.Handle("POST", "/pgw/voids", api.guard(api.Void))                      // void a topic
+	router.Handle("POST", "/pgw/matchers/dry-run", api.guard(api.DryRunMatchers)) // preview matchers of free text topic
+}
+
+// guard platform operations, only moderators or internal services are allowed
+func (api *restapi) guard(next httprouter.Handle) httprouter.Handle {
+	return rest.Authorize(api.auth, rest.Roles(
+		string(userDao.Roles.Platform()),
+		string(userDao.Roles.Moderator()),
+		string(userDao.Roles.Admin()),
+	), next)
 }
 
 // Login to platform
@@ -126,12 +154,8 @@ func (api *restapi) Answer(w http.ResponseWriter, r *http.Request, p httprouter.
 		return
 	}
 
-	// go func() {
-	// 	stat, err := api.pgw.Answer(ctx, ans)
-	// 	log.Tracef("Answer stat: %+v, err: %v", stat, err)
-	// }()
-
-	stat, err := api.pgw.Answer(ctx, ans)
+	// bets are settled asynchronously, poll the job for progress
+	job, err := api.pgw.Answer(ctx, ans)
 	exc, throw := exception.IsException(err)
 	if throw {
 		res.Error(exc.Message(), err).Respond(exc.Code())
@@ -142,7 +166,137 @@ func (api *restapi) Answer(w http.ResponseWriter, r *http.Request, p httprouter.
 		return
 	}
 
-	res.Payload(stat).Respond(http.StatusOK)
+	res.Payload(job).Respond(http.StatusAccepted)
+}
+
+// PreviewAnswer of a topic, nothing is settled
+func (api *restapi) PreviewAnswer(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	ans := &command.Answer{}
+	if err := defaultRequestUnwrapper(ans)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	preview, err := api.pgw.PreviewAnswer(ctx, ans)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to preview answer of a topic", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(preview).Respond(http.StatusOK)
+}
+
+// Correct answer of a topic, progress is polled as answering
+func (api *restapi) Correct(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	cor := &command.Correct{}
+	if err := defaultRequestUnwrapper(cor)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	job, err := api.pgw.Correct(ctx, cor)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to correct a topic", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(job).Respond(http.StatusAccepted)
+}
+
+// Void a topic, progress is polled as answering
+func (api *restapi) Void(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	vd := &command.Void{}
+	if err := defaultRequestUnwrapper(vd)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	job, err := api.pgw.Void(ctx, vd)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to void a topic", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(job).Respond(http.StatusAccepted)
+}
+
+// DryRunMatchers of a free text topic before answering it
+func (api *restapi) DryRunMatchers(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	ctx := r.Context()
+	ans := &command.Answer{}
+	if err := defaultRequestUnwrapper(ans)(r.Body); err != nil {
+		res.Error("Failed to parse request body", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	result, err := api.pgw.DryRunMatchers(ctx, ans)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to dry run matchers", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(result).Respond(http.StatusOK)
+}
+
+// AnswerJob progress of answering a topic
+func (api *restapi) AnswerJob(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	job, err := api.pgw.AnswerJob(r.Context(), p.ByName("job"))
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to get answering progress", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(job).Respond(http.StatusOK)
+}
+
+// RetryAnswer of a failed job
+func (api *restapi) RetryAnswer(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+	job, err := api.pgw.RetryAnswer(r.Context(), p.ByName("job"))
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to retry answering", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(job).Respond(http.StatusAccepted)
 }
 
 // @obj: please send a pointer to a struct
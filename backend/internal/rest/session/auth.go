@@ -0,0 +1,86 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	firebase "firebase.google.com/go"
+	"firebase.google.com/go/auth"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+)
+
+// Auth client of firebase, every method used by gateways
+type Auth interface {
+	AuthClient
+	VerifyIDTokenAndCheckRevoked(ctx context.Context, token string) (*auth.Token, error)
+	SessionCookie(ctx context.Context, token string, dur time.Duration) (string, error)
+}
+
+// NewAuth client of firebase, configured by GOOGLE_APPLICATION_CREDENTIALS
+// firebase is skipped only if OFFLINE_AUTH is true (default false), for local development,
+// token and session are then the email of the user, optionally followed by #role, e.g. me@local#admin
+func NewAuth(ctx context.Context) Auth {
+	if offline() {
+		log.Warnln("!!! OFFLINE_AUTH is enabled, sessions are NOT verified by firebase !!!")
+		log.Warnln("!!! Anyone can act as any user and role, including admin, never enable it outside local development !!!")
+		return offlineAuth{}
+	}
+
+	fap, err := firebase.NewApp(ctx, nil)
+	if err != nil {
+		log.Fatalln("Failed to initialize firebase:", err)
+	}
+	fac, err := fap.Auth(ctx)
+	if err != nil {
+		log.Fatalln("Failed to initialize firebase auth:", err)
+	}
+
+	return fac
+}
+
+// offline auth is explicitly enabled, regardless of where repositories are kept
+func offline() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("OFFLINE_AUTH"))
+	return enabled
+}
+
+// offlineAuth trusting any token, for local development only
+type offlineAuth struct{}
+
+func (offlineAuth) VerifySessionCookie(ctx context.Context, cookie string) (*auth.Token, error) {
+	return offlineToken(cookie)
+}
+
+func (offlineAuth) VerifyIDTokenAndCheckRevoked(ctx context.Context, token string) (*auth.Token, error) {
+	return offlineToken(token)
+}
+
+// SessionCookie is the token itself
+func (offlineAuth) SessionCookie(ctx context.Context, token string, dur time.Duration) (string, error) {
+	_, err := offlineToken(token)
+	return token, err
+}
+
+// offlineToken of email optionally followed by #role
+func offlineToken(token string) (*auth.Token, error) {
+	email, role := token, ""
+	if i := strings.LastIndex(token, "#"); i >= 0 {
+		email, role = token[:i], token[i+1:]
+	}
+	if !strings.Contains(email, "@") {
+		return nil, exception.New(http.StatusUnauthorized, "Offline token must be an email, optionally followed by #role")
+	}
+
+	claims := map[string]interface{}{"email": email}
+	if role != "" {
+		claims["role"] = role
+	}
+
+	return &auth.Token{Subject: email, UID: email, Claims: claims}, nil
+}
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestOfflineAuth(t *testing.T) {
+	os.Setenv("OFFLINE_AUTH", "true")
+	defer os.Unsetenv("OFFLINE_AUTH")
+
+	ctx := context.Background()
+	a := New(NewAuth(ctx), "")
+
+	tests := []struct {
+		name      string
+		token     string
+		wantEmail string
+		wantRole  string
+		wantErr   bool
+	}{
+		{"email", "me@local", "me@local", "gambler", false},
+		{"email with role", "me@local#moderator", "me@local", "moderator", false},
+		{"platform role is not granted", "me@local#platform", "me@local", "gambler", false},
+		{"not an email", "me", "", "", true},
+		{"role without email", "#admin", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := NewAuth(ctx).SessionCookie(ctx, tt.token, 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SessionCookie() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Cookie", CookieName+"="+session)
+			id, err := a.Authenticate(r)
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if id.Email != tt.wantEmail || id.Roles[0] != tt.wantRole {
+				t.Errorf("Authenticate() = %s as %v, want %s as %s", id.Email, id.Roles, tt.wantEmail, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestOfflineIsExplicit(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want bool
+	}{
+		{"default", map[string]string{}, false},
+		{"memory repositories", map[string]string{"REPO_DRIVER": "memory"}, false},
+		{"enabled", map[string]string{"OFFLINE_AUTH": "true"}, true},
+		{"disabled", map[string]string{"REPO_DRIVER": "memory", "OFFLINE_AUTH": "false"}, false},
+		{"not a bool", map[string]string{"OFFLINE_AUTH": "yes please"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REPO_DRIVER", "")
+			t.Setenv("OFFLINE_AUTH", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			if got := offline(); got != tt.want {
+				t.Errorf("offline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package session resolves identity of HTTP request,
+// either from firebase session cookie or from internal service key
+package session
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"firebase.google.com/go/auth"
+
+	"github.com/di-collective/ditebak/backend/internal/domain/user/dao"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/global"
+	"github.com/di-collective/ditebak/backend/pkg/rest"
+)
+
+// CookieName of firebase session
+const CookieName = "fa-session"
+
+// AuthClient masks firebase auth
+type AuthClient interface {
+	VerifySessionCookie(ctx context.Context, cookie string) (*auth.Token, error)
+}
+
+// Authenticator of HTTP request
+type Authenticator struct {
+	ac         AuthClient
+	serviceKey string
+}
+
+// New session authenticator
+// @ac: firebase auth client
+// @serviceKey: shared secret of internal services, empty disables service authentication
+func New(ac AuthClient, serviceKey string) *Authenticator {
+	return &Authenticator{
+		ac:         ac,
+		serviceKey: serviceKey,
+	}
+}
+
+// Authenticate HTTP request
+// 1. Internal service is identified by service key and gets platform role
+// 2. User is identified by session cookie and gets role from "role" claim, gambler by default
+func (a *Authenticator) Authenticate(r *http.Request) (*rest.Identity, error) {
+	if key := r.Header.Get(global.Header.ServiceKey()); key != "" {
+		if a.serviceKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(a.serviceKey)) != 1 {
+			return nil, exception.New(http.StatusUnauthorized, "Invalid service key")
+		}
+
+		return &rest.Identity{
+			Email: r.Header.Get(global.Header.ActingAs()),
+			Roles: []string{string(dao.Roles.Platform())},
+		}, nil
+	}
+
+	session, err := r.Cookie(CookieName)
+	if err != nil || session.Value == "" {
+		return nil, nil
+	}
+
+	jwtok, err := a.ac.VerifySessionCookie(r.Context(), session.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	email, _ := jwtok.Claims["email"].(string)
+	role, _ := jwtok.Claims["role"].(string)
+	if role == "" || role == string(dao.Roles.Platform()) {
+		role = string(dao.Roles.Gambler())
+	}
+
+	return &rest.Identity{
+		Email: email,
+		Roles: []string{role},
+	}, nil
+}
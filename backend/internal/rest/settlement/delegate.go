@@ -0,0 +1,36 @@
+package settlement
+
+import (
+	"time"
+
+	"github.com/di-collective/ditebak/backend/internal/domain/settlement"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type delegate struct{}
+
+func (del *delegate) Constructor() interface{} {
+	return &settlement.Job{}
+}
+
+func (del *delegate) WillCreate(data interface{}) {
+	now := time.Now()
+	job := data.(*settlement.Job)
+	job.CreatedAt = &now
+}
+
+func (del *delegate) DidCreate(created interface{}, id primitive.ObjectID) {
+	job := created.(*settlement.Job)
+	job.ID = id
+}
+
+func (del *delegate) WillUpdate(data interface{}, opt *options.UpdateOptions) {
+	now := time.Now()
+	job := data.(*settlement.Job)
+	job.UpdatedAt = &now
+}
+
+func (del *delegate) DidUpdate(data interface{}, upsert *primitive.ObjectID) {
+	// do nothing, job is never upserted
+}
@@ -0,0 +1,225 @@
+package settlement
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	betDao "github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
+	"github.com/di-collective/ditebak/backend/internal/domain/settlement"
+	userDao "github.com/di-collective/ditebak/backend/internal/domain/user/dao"
+	"github.com/di-collective/ditebak/backend/internal/rest/bet"
+	"github.com/di-collective/ditebak/backend/internal/rest/ledger"
+	"github.com/di-collective/ditebak/backend/internal/rest/topic"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/repo/driver"
+	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+	typedRepo "github.com/di-collective/ditebak/backend/pkg/repo/typed"
+	"github.com/di-collective/ditebak/backend/pkg/rest"
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// indexes of settlement job collection, unfinished jobs are looked up on resume
+var indexes = []mongorepo.Index{
+	{Name: "topic_created_at", Keys: bson.D{{Key: "topic", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Name: "state", Keys: bson.D{{Key: "state", Value: 1}}},
+}
+
+// restapi of settlement
+type restapi struct {
+	svc  *settlement.Service
+	auth rest.Authenticator
+}
+
+// resumeInterval between rounds of resuming unfinished jobs, picks up jobs left stale by a replica which died
+const resumeInterval = time.Minute
+
+// New instance of Settlement REST API
+// unfinished jobs are resumed in background, periodically, each by the one replica claiming it
+// @topics, @bets, @users, @entries, @jobs: mongo collections, must belong to the same client to share transaction
+// @auth: resolves identity of requester
+func New(topics, bets, users, entries, jobs *mongo.Collection, auth rest.Authenticator) rest.REST {
+	betRepo := bet.NewRepo(bets)
+	jobRepo := NewRepo(jobs)
+	if _, err := jobRepo.SyncIndexes(context.Background()); err != nil {
+		log.Errorln("Failed to sync indexes of settlement jobs:", err)
+	}
+
+	api := &restapi{
+		svc: settlement.New(
+			/* transactor */ betRepo,
+			/* topics     */ topic.NewService(topics),
+			/* bets       */ typedRepo.Of[*betDao.Bet, *betDao.Bet](betRepo),
+			/* ledger     */ ledger.NewService(entries, users),
+			/* jobs       */ jobRepo),
+		auth: auth,
+	}
+
+	go func() {
+		for {
+			if err := api.svc.Resume(context.Background()); err != nil {
+				log.Errorln("Failed to resume settlement jobs:", err)
+			}
+			time.Sleep(resumeInterval)
+		}
+	}()
+
+	return api
+}
+
+// WithRouter initialize routes using julienschmidth httprouter
+func (api *restapi) WithRouter(router *httprouter.Router) {
+	rule := rest.Roles(string(userDao.Roles.Platform()))
+	router.POST("/settlements", rest.Authorize(api.auth, rule, api.Settle))
+	router.GET("/settlements/:id", rest.Authorize(api.auth, rule, api.Get))
+	router.POST("/settlements/:id/retry", rest.Authorize(api.auth, rule, api.Retry))
+
+	router.POST("/previews", rest.Authorize(api.auth, rule, api.Preview))
+
+	router.GET("/matchers", rest.Authorize(api.auth, rule, api.Matchers))
+	router.POST("/matchers/dry-run", rest.Authorize(api.auth, rule, api.DryRun))
+}
+
+// Settle a topic by answering, correcting or voiding it, responds with the job settling it
+func (api *restapi) Settle(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	ctx := r.Context()
+	res := rest.NewAPIResponse(w, r)
+
+	cmd := &settlement.Command{}
+	if err := rest.ParseBody(r, cmd); err != nil {
+		res.Error("Failed to parse payload", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	by := ""
+	if who := rest.IdentityOf(ctx); who != nil {
+		by = who.Email
+	}
+
+	job, err := api.svc.Submit(ctx, cmd, by)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to settle topic", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(job).Respond(http.StatusAccepted)
+}
+
+// Get progress of a job
+func (api *restapi) Get(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	res := rest.NewAPIResponse(w, r)
+
+	job, err := api.svc.Get(r.Context(), id)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error(fmt.Sprintf("Failed to get settlement job with id: %s", id), err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(job).Respond(http.StatusOK)
+}
+
+// Retry a failed job
+func (api *restapi) Retry(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	res := rest.NewAPIResponse(w, r)
+
+	job, err := api.svc.Retry(r.Context(), id)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error(fmt.Sprintf("Failed to retry settlement job with id: %s", id), err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(job).Respond(http.StatusAccepted)
+}
+
+// Preview settlement of a topic without writing anything
+func (api *restapi) Preview(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+
+	cmd := &settlement.Command{}
+	if err := rest.ParseBody(r, cmd); err != nil {
+		res.Error("Failed to parse payload", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	preview, err := api.svc.Preview(r.Context(), cmd)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to preview settlement", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(preview).Respond(http.StatusOK)
+}
+
+// Matchers available to free text topic
+func (api *restapi) Matchers(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	rest.NewAPIResponse(w, r).Payload(settlement.MatcherNames()).Respond(http.StatusOK)
+}
+
+// DryRun every matcher against bets of a topic
+func (api *restapi) DryRun(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	res := rest.NewAPIResponse(w, r)
+
+	ans := &settlement.Answer{}
+	if err := rest.ParseBody(r, ans); err != nil {
+		res.Error("Failed to parse payload", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	result, err := api.svc.DryRun(r.Context(), ans)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error("Failed to dry run matchers", err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(result).Respond(http.StatusOK)
+}
+
+// NewRepo of settlement job
+func NewRepo(coll *mongo.Collection) driver.Repo {
+	delegate := &delegate{}
+	return driver.New(
+		/* collection    */ coll,
+		/* default sort  */ map[string]int{"created_at": -1},
+		/* constructor   */ delegate.Constructor,
+		/* event handler */ delegate,
+		/* indexes       */ indexes...)
+}
+
+// Teardown REST API
+func Teardown(ctx context.Context) error {
+	// TODO: graceful shutdown
+	log.Info("SHUTTING DOWN")
+	return nil
+}
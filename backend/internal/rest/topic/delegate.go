@@ -11,33 +11,27 @@ import (
 
 type delegate struct{}
 
-func (del *delegate) Constructor() interface{} {
+func (del *delegate) Constructor() *dao.Topic {
 	return &dao.Topic{}
 }
 
-func (del *delegate) WillCreate(data interface{}) {
+func (del *delegate) WillCreate(topic *dao.Topic) {
 	now := time.Now()
-	topic := data.(*dao.Topic)
 	topic.CreatedAt = &now
 	topic.State = dao.TopicStates.Draft()
+	topic.Transitions = nil
 }
 
-func (del *delegate) DidCreate(created interface{}, id primitive.ObjectID) {
-	topic := created.(*dao.Topic)
+func (del *delegate) DidCreate(topic *dao.Topic, id primitive.ObjectID) {
 	topic.ID = id
 }
 
-func (del *delegate) WillUpdate(data interface{}, opt *options.UpdateOptions) {
+// WillUpdate draft, state and answer are mutated by topic service instead
+func (del *delegate) WillUpdate(topic *dto.Draft, opt *options.UpdateOptions) {
 	now := time.Now()
-	topic := data.(*dto.Topic)
 	topic.UpdatedAt = &now
-
-	opt.SetUpsert(true)
 }
 
-func (del *delegate) DidUpdate(data interface{}, upsert *primitive.ObjectID) {
-	if upsert != nil {
-		topic := data.(*dto.Topic)
-		topic.ID = *upsert
-	}
+func (del *delegate) DidUpdate(topic *dto.Draft, upsert *primitive.ObjectID) {
+	// do nothing, draft is never upserted
 }
@@ -1,20 +1,43 @@
 package dto
 
-import (
-	"time"
+import "time"
 
-	"go.mongodb.org/mongo-driver/bson/primitive"
-)
+// Draft partial update, only draft topic is edited this way
+// state is changed through Transition, answer and difficulty by settlement, author on create
+type Draft struct {
+	UpdatedAt *time.Time `json:"updated_at,omitempty" bson:"updated_at,omitempty"` // set on update
+	ClosingAt *time.Time `json:"closing_at,omitempty" bson:"closing_at,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty" bson:"publish_at,omitempty"`
+	Banner    string     `json:"banner,omitempty" bson:"banner,omitempty"`
+	Question  string     `json:"question,omitempty" bson:"question,omitempty"`
+	Context   string     `json:"context,omitempty" bson:"context,omitempty"`
 
-// Topic database object
-type Topic struct {
-	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	CreatedAt *time.Time         `json:"created_at,omitempty" bson:"created_at,omitempty"`
-	UpdatedAt *time.Time         `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
-	ClosingAt *time.Time         `json:"closing_at,omitempty" bson:"closing_at,omitempty"`
-	Banner    string             `json:"banner,omitempty" bson:"banner,omitempty"`
-	Question  string             `json:"question,omitempty" bson:"question,omitempty"`
-	Answer    string             `json:"answer,omitempty" bson:"answer,omitempty"`
-	Context   string             `json:"context,omitempty" bson:"context,omitempty"`
-	State     string             `json:"state,omitempty" bson:"state,omitempty"`
+	PayoutMode string    `json:"payout_mode,omitempty" bson:"payout_mode,omitempty"`
+	Burn       *int      `json:"burn,omitempty" bson:"burn,omitempty"`
+	Weighted   *bool     `json:"weighted,omitempty" bson:"weighted,omitempty"`
+	EarlyBird  *int      `json:"early_bird,omitempty" bson:"early_bird,omitempty"`
+	Options    []*Option `json:"options,omitempty" bson:"options,omitempty"`
+	Kind       string    `json:"kind,omitempty" bson:"kind,omitempty"`
+	Bands      []*Band   `json:"bands,omitempty" bson:"bands,omitempty"`
+	Rule       string    `json:"rule,omitempty" bson:"rule,omitempty"`
+	Matcher    string    `json:"matcher,omitempty" bson:"matcher,omitempty"`
+	MaxEdits   *int      `json:"max_edits,omitempty" bson:"max_edits,omitempty"`
+}
+
+// Band of tolerance of number or date topic
+type Band struct {
+	Within float64 `json:"within" bson:"within"`
+	Reward int     `json:"reward" bson:"reward"`
+}
+
+// Option of multiple-choice topic
+type Option struct {
+	ID          string `json:"id" bson:"id"`
+	Label       string `json:"label" bson:"label"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+}
+
+// Transition of topic state
+type Transition struct {
+	State string `json:"state"`
 }
@@ -2,43 +2,156 @@ package topic
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"reflect"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
+	"github.com/di-collective/ditebak/backend/internal/domain/topic/service"
+	userDao "github.com/di-collective/ditebak/backend/internal/domain/user/dao"
 	"github.com/di-collective/ditebak/backend/internal/rest/topic/dto"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
 	"github.com/di-collective/ditebak/backend/pkg/queryables"
+	"github.com/di-collective/ditebak/backend/pkg/repo/driver"
 	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+	typedRepo "github.com/di-collective/ditebak/backend/pkg/repo/typed"
 	"github.com/di-collective/ditebak/backend/pkg/rest"
-	"github.com/di-collective/ditebak/backend/pkg/service/basic"
+	typedService "github.com/di-collective/ditebak/backend/pkg/service/typed"
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// restapi of topic, generic CRUD plus state transition
+type restapi struct {
+	rest.REST
+	svc  *service.Service
+	auth rest.Authenticator
+	rule *rest.Rule
+}
+
+// indexes of topic collection
+var indexes = []mongorepo.Index{
+	{Name: "state_closing_at", Keys: bson.D{{Key: "state", Value: 1}, {Key: "closing_at", Value: 1}}},
+	{Name: "state_publish_at", Keys: bson.D{{Key: "state", Value: 1}, {Key: "publish_at", Value: 1}}},
+	{Name: "question_context_text", Keys: bson.D{{Key: "question", Value: "text"}, {Key: "context", Value: "text"}}},
+}
+
 // New instance of Topic REST API
-func New(coll *mongo.Collection) rest.REST {
+// @coll: mongo collection
+// @auth: resolves identity of requester
+func New(coll *mongo.Collection, auth rest.Authenticator) rest.REST {
 	delegate := &delegate{}
-	return rest.New(&rest.Config{
-		Resource: "topics",
-		Service: basic.New(mongorepo.New(
-			/* collection   */ coll,
-			/* default sort */ map[string]int{"created_at": -1},
-			/* constructor  */ delegate.Constructor,
-			/* id assigner  */ delegate)),
-		CreatePayload: delegate.Constructor, //dto = dao
-		UpdatePayload: func() interface{} {
-			//uses dto.Topic to allow partial update
-			return &dto.Topic{}
-		},
-		Queryables: queryables.Collection{
-			{DtoKey: "state", DaoKey: "state", TypeOf: reflect.Array,
-				// (state=a,b,c) --> state: {$in: [a, b, c]}
-				Transform: func(key string, value interface{}) (string, interface{}) {
-					return key, map[string]interface{}{
-						"$in": value,
-					}
-				}},
-		},
-	})
+	admin, moderator, platform := string(userDao.Roles.Admin()), string(userDao.Roles.Moderator()), string(userDao.Roles.Platform())
+	svc := NewService(coll)
+	if _, err := NewRepo(coll).SyncIndexes(context.Background()); err != nil {
+		log.Errorln("Failed to sync indexes of topics:", err)
+	}
+
+	return &restapi{
+		svc:  svc,
+		auth: auth,
+		rule: rest.Roles(platform), // moderator moves topics through /mgw
+		REST: rest.NewTyped(&rest.Typed[*dao.Topic, *dto.Draft]{
+			Resource:      "topics",
+			Service:       typedService.Of[*dao.Topic, *dto.Draft](svc),
+			CreatePayload: delegate.Constructor, //dto = dao
+			UpdatePayload: func() *dto.Draft {
+				//uses dto.Draft to only allow partial update of draft
+				return &dto.Draft{}
+			},
+			Queryables: queryables.Collection{
+				{DtoKey: "state", DaoKey: "state", TypeOf: reflect.Array,
+					// (state=a,b,c) --> state: {$in: [a, b, c]}
+					Transform: func(key string, value interface{}) (string, interface{}) {
+						return key, map[string]interface{}{
+							"$in": value,
+						}
+					}},
+				// (closing_before=RFC3339) --> closing_at: {$lte: time}
+				{DtoKey: "closing_before", DaoKey: "closing_at", TypeOf: reflect.String, Transform: until},
+				// (publish_before=RFC3339) --> publish_at: {$lte: time}
+				{DtoKey: "publish_before", DaoKey: "publish_at", TypeOf: reflect.String, Transform: until},
+			},
+			Auth: auth,
+			Policy: rest.Policy{
+				rest.VerbFind:   rest.Public(),
+				rest.VerbGet:    rest.Public(),
+				rest.VerbCreate: rest.Roles(platform), // moderator drafts through /mgw
+				rest.VerbUpdate: rest.Roles(platform), // moderator edits through /mgw
+				rest.VerbDelete: rest.Roles(moderator, admin),
+				rest.VerbRemove: rest.Roles(admin),
+			},
+		}),
+	}
+}
+
+// WithRouter initialize routes using julienschmidth httprouter
+func (api *restapi) WithRouter(router *httprouter.Router) {
+	api.REST.WithRouter(router)
+	router.POST("/topics/:id/transitions", rest.Authorize(api.auth, api.rule, api.Transition))
+}
+
+// Transition topic into another state
+func (api *restapi) Transition(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	id := p.ByName("id")
+	ctx := r.Context()
+	res := rest.NewAPIResponse(w, r)
+
+	trs := &dto.Transition{}
+	if err := rest.ParseBody(r, trs); err != nil {
+		res.Error("Failed to parse payload", err).Respond(http.StatusBadRequest)
+		return
+	}
+
+	by := ""
+	if who := rest.IdentityOf(ctx); who != nil {
+		by = who.Email
+	}
+
+	result, err := api.svc.Transition(ctx, id, trs.State, by)
+	exc, throw := exception.IsException(err)
+	if throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	} else if err != nil {
+		res.Error(fmt.Sprintf("Failed to transition [topics] with id: %s", id), err).
+			Respond(http.StatusInternalServerError)
+		return
+	}
+
+	res.Payload(result).Respond(http.StatusOK)
+}
+
+// NewService of topic, shared with other resources operating on topics
+func NewService(coll *mongo.Collection) *service.Service {
+	return service.New(NewRepo(coll))
+}
+
+// NewRepo of topic
+func NewRepo(coll *mongo.Collection) driver.Repo {
+	delegate := typedRepo.Delegate[*dao.Topic, *dto.Draft](&delegate{})
+	return driver.New(
+		/* collection   */ coll,
+		/* default sort */ map[string]int{"created_at": -1},
+		/* constructor  */ delegate.Constructor,
+		/* id assigner  */ delegate,
+		/* indexes      */ indexes...)
+}
+
+// until transform RFC3339 time query into less than or equal query
+func until(key string, value interface{}) (string, interface{}) {
+	t, err := time.Parse(time.RFC3339, value.(string))
+	if err != nil {
+		return key, value
+	}
+
+	return key, map[string]interface{}{
+		"$lte": t,
+	}
 }
 
 // Teardown REST API
@@ -11,34 +11,30 @@ import (
 
 type delegate struct{}
 
-func (del *delegate) Constructor() interface{} {
+func (del *delegate) Constructor() *dao.User {
 	return &dao.User{
 		Reputation: 0,
 	}
 }
 
-func (del *delegate) WillCreate(data interface{}) {
+func (del *delegate) WillCreate(user *dao.User) {
 	now := time.Now()
-	user := data.(*dao.User)
 	user.CreatedAt = &now
 }
 
-func (del *delegate) DidCreate(created interface{}, id primitive.ObjectID) {
-	user := created.(*dao.User)
+func (del *delegate) DidCreate(user *dao.User, id primitive.ObjectID) {
 	user.ID = id
 }
 
-func (del *delegate) WillUpdate(data interface{}, opt *options.UpdateOptions) {
+func (del *delegate) WillUpdate(user *dto.User, opt *options.UpdateOptions) {
 	now := time.Now()
-	user := data.(*dto.User)
 	user.UpdatedAt = &now
 
 	opt.SetUpsert(true)
 }
 
-func (del *delegate) DidUpdate(data interface{}, upsert *primitive.ObjectID) {
+func (del *delegate) DidUpdate(user *dto.User, upsert *primitive.ObjectID) {
 	if upsert != nil {
-		user := data.(*dto.User)
 		user.ID = *upsert
 	}
 }
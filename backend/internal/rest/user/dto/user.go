@@ -4,9 +4,36 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/di-collective/ditebak/backend/internal/domain/user/dao"
 )
 
-// User dto
+// NewUser payload of create, reputation is only opened through the ledger
+type NewUser struct {
+	VerifiedAt  *time.Time `json:"verified_at,omitempty"`
+	Provider    string     `json:"provider,omitempty"`
+	Email       string     `json:"email,omitempty"`
+	DisplayName string     `json:"display_name,omitempty"`
+	FirstName   string     `json:"first_name,omitempty"`
+	LastName    string     `json:"last_name,omitempty"`
+	Photo       string     `json:"photo,omitempty"`
+}
+
+// User created of the payload, without reputation, unknown provider is left empty
+func (u *NewUser) User() *dao.User {
+	provider, _ := dao.Providers.Parse(u.Provider)
+	return &dao.User{
+		VerifiedAt:  u.VerifiedAt,
+		Provider:    provider,
+		Email:       u.Email,
+		DisplayName: u.DisplayName,
+		FirstName:   u.FirstName,
+		LastName:    u.LastName,
+		Photo:       u.Photo,
+	}
+}
+
+// User dto, reputation can only be changed through the ledger
 type User struct {
 	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
 	CreatedAt   *time.Time         `json:"created_at,omitempty" bson:"created_at,omitempty"`
@@ -18,5 +45,4 @@ type User struct {
 	FirstName   string             `json:"first_name,omitempty" bson:"first_name,omitempty"`
 	LastName    string             `json:"last_name,omitempty" bson:"last_name,omitempty"`
 	Photo       *string            `json:"photo,omitempty" bson:"photo,omitempty"`
-	Reputation  *int64             `json:"reputation,omitempty" bson:"reputation,omitempty"`
 }
@@ -6,36 +6,74 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	userDao "github.com/di-collective/ditebak/backend/internal/domain/user/dao"
 	"github.com/di-collective/ditebak/backend/internal/rest/user/dto"
 	"github.com/di-collective/ditebak/backend/pkg/queryables"
+	"github.com/di-collective/ditebak/backend/pkg/repo/driver"
 	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+	typedRepo "github.com/di-collective/ditebak/backend/pkg/repo/typed"
 	"github.com/di-collective/ditebak/backend/pkg/rest"
-	"github.com/di-collective/ditebak/backend/pkg/service/basic"
+	typedService "github.com/di-collective/ditebak/backend/pkg/service/typed"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// indexes of user collection
+var indexes = []mongorepo.Index{
+	{Name: "email", Keys: bson.D{{Key: "email", Value: 1}}},
+	{Name: "provider", Keys: bson.D{{Key: "provider", Value: 1}}},
+}
+
 // New instance of User REST API
-func New(coll *mongo.Collection) rest.REST {
-	delegate := &delegate{}
-	return rest.New(&rest.Config{
-		Resource: "users",
-		Service: basic.New(mongorepo.New(
-			/* collection   */ coll,
-			/* default sort */ map[string]int{"created_at": -1},
-			/* constructor  */ delegate.Constructor,
-			/* id assigner  */ delegate)),
-		CreatePayload: delegate.Constructor,
-		UpdatePayload: func() interface{} {
-			return &dto.User{}
+// @coll: mongo collection
+// @auth: resolves identity of requester
+func New(coll *mongo.Collection, auth rest.Authenticator) rest.REST {
+	admin, platform := string(userDao.Roles.Admin()), string(userDao.Roles.Platform())
+	rps := NewRepo(coll)
+	if _, err := rps.SyncIndexes(context.Background()); err != nil {
+		log.Errorln("Failed to sync indexes of users:", err)
+	}
+
+	return rest.NewConverted(&rest.Converted[*dto.NewUser, *userDao.User, *dto.User]{
+		Typed: rest.Typed[*userDao.User, *dto.User]{
+			Resource: "users",
+			Service:  typedService.Basic(typedRepo.Of[*userDao.User, *dto.User](rps)),
+			UpdatePayload: func() *dto.User {
+				return &dto.User{}
+			},
+			Queryables: queryables.Collection{
+				{DtoKey: "provider", DaoKey: "provider", TypeOf: reflect.String},
+				{DtoKey: "email", DaoKey: "email", TypeOf: reflect.String},
+			},
+			Auth: auth,
+			Policy: rest.Policy{
+				rest.VerbFind:   rest.Roles(platform, admin),
+				rest.VerbGet:    rest.Roles(platform, admin),
+				rest.VerbCreate: rest.Roles(platform, admin),
+				rest.VerbUpdate: rest.Roles(platform, admin),
+				rest.VerbDelete: rest.Roles(admin),
+				rest.VerbRemove: rest.Roles(admin),
+			},
 		},
-		Convert: nil, // dto == dao
-		Queryables: queryables.Collection{
-			{DtoKey: "provider", DaoKey: "provider", TypeOf: reflect.String},
-			{DtoKey: "email", DaoKey: "email", TypeOf: reflect.String},
+		CreatePayload: func() *dto.NewUser {
+			//reputation is opened through the ledger
+			return &dto.NewUser{}
 		},
+		Convert: (*dto.NewUser).User,
 	})
 }
 
+// NewRepo of user, shared with other resources operating on users
+func NewRepo(coll *mongo.Collection) driver.Repo {
+	delegate := typedRepo.Delegate[*userDao.User, *dto.User](&delegate{})
+	return driver.New(
+		/* collection   */ coll,
+		/* default sort */ map[string]int{"created_at": -1},
+		/* constructor  */ delegate.Constructor,
+		/* id assigner  */ delegate,
+		/* indexes      */ indexes...)
+}
+
 // Teardown REST API
 func Teardown(ctx context.Context) error {
 	// TODO: graceful shutdown
@@ -0,0 +1,131 @@
+// Package scheduler runs topic lifecycle in background,
+// publishing scheduled drafts and closing topics at their closing time
+package scheduler
+
+import (
+	"context"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/di-collective/ditebak/backend/internal/usecase/platform"
+	"github.com/di-collective/ditebak/backend/pkg/global"
+	"github.com/di-collective/ditebak/backend/pkg/lease"
+)
+
+// Scheduler of topic lifecycle
+type Scheduler struct {
+	pgw      *platform.Gateway
+	lease    *lease.Lease
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New topic scheduler, only one replica holding the lease runs it
+// @coll: mongo collection to store leases
+func New(coll *mongo.Collection) *Scheduler {
+	interval, err := time.ParseDuration(os.Getenv("SCHEDULER_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	conf := &platform.Config{
+		Const: &platform.ConfigConst{
+			ServiceKey: os.Getenv("SERVICE_KEY"),
+		},
+		URL: &platform.ConfigURL{
+			Topic: defaultOnEmptyEnv("URL_TOPIC", "http://localhost:8080/topics"),
+		},
+	}
+
+	return &Scheduler{
+		pgw:      platform.New(conf),
+		lease:    lease.New(coll, "topic-scheduler", 3*interval),
+		interval: interval,
+	}
+}
+
+// Start scheduler in background
+// first run happens immediately to reconcile topics which are due while no replica was running
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+
+			s.run(ctx)
+			timer.Reset(s.interval)
+		}
+	}()
+}
+
+// Teardown stops scheduler and releases its lease
+func (s *Scheduler) Teardown(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+
+	s.cancel()
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	log.Info("SHUTTING DOWN SCHEDULER")
+	return s.lease.Release(ctx)
+}
+
+// run one round of scheduling if this replica holds the lease
+func (s *Scheduler) run(ctx context.Context) {
+	leader, err := s.lease.Acquire(ctx)
+	if err != nil {
+		log.Errorln("Failed to acquire scheduler lease:", err)
+		return
+	}
+	if !leader {
+		log.Traceln("Scheduler lease is held by another replica")
+		return
+	}
+
+	ctx = context.WithValue(ctx, global.Context.Email(), "scheduler")
+	now := time.Now()
+
+	published, err := s.pgw.PublishDue(ctx, now)
+	if err != nil {
+		log.Errorln("Failed to publish scheduled topics:", err)
+	}
+
+	closed, err := s.pgw.CloseDue(ctx, now)
+	if err != nil {
+		log.Errorln("Failed to close due topics:", err)
+	}
+
+	if published > 0 || closed > 0 {
+		log.Infof("Scheduler published %d and closed %d topics", published, closed)
+	}
+}
+
+func defaultOnEmptyEnv(env, def string) string {
+	obj := os.Getenv(env)
+	if obj == "" {
+		obj = def
+	}
+
+	return obj
+}
@@ -2,8 +2,15 @@ package command
 
 // PlaceBet command
 type PlaceBet struct {
-	Topic      string `json:"topic"`      // ID
-	User       string `json:"-"`          // UserID fetched via BE
-	Prediction string `json:"prediction"` // Bet
-	Stake      int    `json:"stake"`      // Reputation
+	Topic       string   `json:"topic"`       // ID
+	User        string   `json:"-"`           // UserID fetched via BE
+	Prediction  string   `json:"prediction"`  // Bet
+	Probability *float64 `json:"probability"` // of yes between 0 and 100, instead of prediction for probability topic
+	Stake       int      `json:"stake"`       // Reputation
+}
+
+// ChangeBet command, stake can't be changed
+type ChangeBet struct {
+	Prediction  string   `json:"prediction"`
+	Probability *float64 `json:"probability"` // of yes between 0 and 100, for probability topic
 }
@@ -15,15 +15,18 @@ type AuthClient interface {
 
 // ConfigConst ...
 type ConfigConst struct {
-	MaxStake   int
-	AuthClient AuthClient
+	MaxStake        int
+	ReputationFloor int64 // reputation can't fall below it after placing a bet
+	AuthClient      AuthClient
+	ServiceKey      string // shared secret to call internal resources
 }
 
 // ConfigURL ...
 type ConfigURL struct {
-	User  string
-	Topic string
-	Bet   string
+	User   string
+	Topic  string
+	Bet    string
+	Ledger string
 
 	Login  string
 	Logout string
@@ -62,6 +65,13 @@ func (conf *Config) GetBetURL(topic, owner string) string {
 	return uri.String()
 }
 
+// GetOneBetURL based on bet id, followed by action if any
+func (conf *Config) GetOneBetURL(id string, action ...string) string {
+	uri, _ := url.Parse(conf.URL.Bet)
+	uri.Path = path.Join(append([]string{uri.Path, id}, action...)...)
+	return uri.String()
+}
+
 // GetMyBetURL based on owner
 func (conf *Config) GetMyBetURL(owner string) string {
 	uri, _ := url.Parse(conf.URL.Bet)
@@ -72,3 +82,19 @@ func (conf *Config) GetMyBetURL(owner string) string {
 	uri.RawQuery = q.Encode()
 	return uri.String()
 }
+
+// GetLedgerURL based on user, paging is taken from query
+func (conf *Config) GetLedgerURL(user string, query url.Values) string {
+	uri, _ := url.Parse(conf.URL.Ledger)
+
+	q := url.Values{}
+	q.Set("user", user)
+	for _, key := range []string{"page", "size"} {
+		if v := query.Get(key); v != "" {
+			q.Set(key, v)
+		}
+	}
+
+	uri.RawQuery = q.Encode()
+	return uri.String()
+}
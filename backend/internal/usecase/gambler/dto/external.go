@@ -16,6 +16,27 @@ type Topic struct {
 	Answer    string             `json:"answer"`
 	Context   string             `json:"context"`
 	State     string             `json:"state"`
+	Options   []*Option          `json:"options"` // empty is free text topic
+	Kind      string             `json:"kind"`    // text, number or date
+	Bands     []*Band            `json:"bands"`   // tolerance bands of number or date
+	Rule      string             `json:"rule"`    // scoring rule of probability
+
+	Weighted   bool `json:"weighted"`             // reward is scaled by difficulty
+	EarlyBird  int  `json:"early_bird"`           // bonus percentage of earliest prediction
+	Difficulty *int `json:"difficulty,omitempty"` // percentage of bettors who got it wrong, once answered
+}
+
+// Band of tolerance, prediction within distance of the answer is rewarded a percentage of its stake
+type Band struct {
+	Within float64 `json:"within"`
+	Reward int     `json:"reward"`
+}
+
+// Option of multiple-choice topic
+type Option struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
 }
 
 // User dto
@@ -34,16 +55,45 @@ type User struct {
 
 // Bet dto
 type Bet struct {
-	ID         string     `json:"id,omitempty"`
-	TopicID    string     `json:"topic_id"`
-	CreatedAt  *time.Time `json:"created_at"`
-	Owner      string     `json:"owner"`      // who made the bet (email)
-	Prediction string     `json:"prediction"` // whats his/her prediction
-	Reputation int        `json:"reputation"` // how many reputation at stake
-	State      string     `json:"state"`
+	ID          string     `json:"id,omitempty"`
+	TopicID     string     `json:"topic_id"`
+	CreatedAt   *time.Time `json:"created_at"`
+	Owner       string     `json:"owner"`                 // who made the bet (email)
+	Prediction  string     `json:"prediction"`            // whats his/her prediction
+	Probability *float64   `json:"probability,omitempty"` // of yes, for probability topic
+	Reputation  int        `json:"reputation"`            // how many reputation at stake
+	State       string     `json:"state"`
+	Multiplier  float64    `json:"multiplier,omitempty"` // early bird multiplier of reward if won, only shown once placed
+	Fee         int64      `json:"fee,omitempty"`        // kept from stake when cancelled
+	Revisions   []*struct {
+		Prediction  string     `json:"prediction"`
+		Probability *float64   `json:"probability,omitempty"`
+		At          *time.Time `json:"at"`
+	} `json:"revisions,omitempty"` // replaced predictions, oldest first
 }
 
 // Wrapper to data
 type Wrapper struct {
 	Data interface{} `json:"data"`
 }
+
+// HasOption check whether option ID is declared by the topic
+func (t *Topic) HasOption(id string) bool {
+	for _, opt := range t.Options {
+		if opt.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OptionIDs of the topic
+func (t *Topic) OptionIDs() []string {
+	ids := make([]string, len(t.Options))
+	for i, opt := range t.Options {
+		ids[i] = opt.ID
+	}
+
+	return ids
+}
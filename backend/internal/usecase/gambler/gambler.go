@@ -5,14 +5,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	resty "github.com/go-resty/resty/v2"
 	log "github.com/sirupsen/logrus"
 
+	betDao "github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
+	topicDao "github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
 	"github.com/di-collective/ditebak/backend/internal/usecase/gambler/command"
 	"github.com/di-collective/ditebak/backend/internal/usecase/gambler/dto"
 	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/global"
 )
 
 type req struct {
@@ -35,7 +39,7 @@ type Gateway struct {
 func New(conf *Config) *Gateway {
 	gw := &Gateway{
 		conf: conf,
-		rc:   resty.New().EnableTrace(),
+		rc:   resty.New().EnableTrace().SetHeader(global.Header.ServiceKey(), conf.Const.ServiceKey),
 		ac:   conf.Const.AuthClient,
 	}
 
@@ -93,6 +97,26 @@ func (gw *Gateway) MyBets(ctx context.Context, email string) ([]byte, error) {
 	})
 }
 
+// MyLedger history of reputation forwarded from API, latest first
+func (gw *Gateway) MyLedger(ctx context.Context, email string, query url.Values) ([]byte, error) {
+	user, err := gw.MyProfile(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []byte
+	return result, gw.doReq(ctx, &req{
+		mtd: "GET",
+		res: "ledger",
+		url: gw.conf.GetLedgerURL(user.ID, query),
+		err: defaultResponseHandler,
+		parse: func(b []byte) error {
+			result = b
+			return nil
+		},
+	})
+}
+
 // PlaceBet ...
 // Verification:
 // 1. Reputation at stake must be more than 0 and can't be more than configured maximum
@@ -100,9 +124,14 @@ func (gw *Gateway) MyBets(ctx context.Context, email string) ([]byte, error) {
 // 3. Topic must exists
 // 	  - state is published
 //    - not expired at closing time
-// 4. Cannot bet more than once
+//    - prediction is one of its option ID, if multiple-choice
+//    - prediction is a number or date, if scored by distance
+//    - probability is between 0 and 100, if probability topic
+// 4. Cannot bet more than once, enforced by bet resource
+// 5. Reputation after stake can't fall below the floor
 // Then:
-// create / place the bet!
+// create / place the bet! stake is escrowed by bet resource
+// early bird multiplier is shown, reward is multiplied by it at settlement if won
 func (gw *Gateway) PlaceBet(ctx context.Context, email string, pb *command.PlaceBet) (*dto.Bet, error) {
 	// verify PB command
 	if pb.Stake < 1 || pb.Stake > gw.conf.Const.MaxStake {
@@ -114,7 +143,6 @@ func (gw *Gateway) PlaceBet(ctx context.Context, email string, pb *command.Place
 
 	users := []*dto.User{}
 	topic := &dto.Topic{}
-	bets := []*dto.Bet{}
 
 	reqs := []*req{{
 		res: "users",
@@ -132,58 +160,194 @@ func (gw *Gateway) PlaceBet(ctx context.Context, email string, pb *command.Place
 		url:   gw.conf.GetTopicURL(pb.Topic),
 		err:   defaultResponseHandler,
 		parse: defaultResponseUnwrapper(topic),
-	}, {
-		res:   "bets",
-		mtd:   "GET",
-		url:   gw.conf.GetBetURL(pb.Topic, email),
-		err:   defaultResponseHandler,
-		parse: defaultResponseUnwrapper(&bets),
 	}}
 
-	// fetch user, topic, and bet
+	// fetch user and topic
 	for _, req := range reqs {
 		if err := gw.doReq(ctx, req); err != nil {
 			return nil, err
 		}
 	}
 
-	log.Tracef("PlaceBet User: %+v, Topic: %+v, Bets: %+v\n", users, topic, bets)
+	log.Tracef("PlaceBet User: %+v, Topic: %+v\n", users, topic)
 
 	// if user is not found
 	if len(users) <= 0 {
 		return nil, exception.New(http.StatusUnauthorized, "You are not authenticated. Please login before placing bet")
 	}
 
-	// verify topic
+	// verify topic and prediction
+	if err := verifyOpen(topic); err != nil {
+		return nil, err
+	}
+	if err := verifyPrediction(topic, pb.Prediction, pb.Probability); err != nil {
+		return nil, err
+	}
+
+	// verify balance, bet resource checks it again atomically
+	user := users[0]
+	if user.Reputation-int64(pb.Stake) < gw.conf.Const.ReputationFloor {
+		return nil, exception.New(http.StatusUnprocessableEntity, "Insufficient reputation, you have %d and must keep at least %d", user.Reputation, gw.conf.Const.ReputationFloor)
+	}
+
+	// create a bet
+	bet, err := gw.createBet(ctx, &dto.Bet{
+		Owner:       user.ID,
+		TopicID:     pb.Topic,
+		Prediction:  pb.Prediction,
+		Probability: pb.Probability,
+		Reputation:  pb.Stake,
+	})
+	if exc, ok := exception.IsException(err); ok && exc.Code() == http.StatusConflict {
+		return nil, exception.New(http.StatusConflict, "Bet already exists")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if topic.EarlyBird > 0 {
+		bonus := topicDao.EarlyBonus(topic.EarlyBird, topic.CreatedAt, topic.ClosingAt, bet.CreatedAt)
+		bet.Multiplier = float64(100+bonus) / 100
+	}
+	return bet, nil
+}
+
+// ChangeBet ...
+// Verification:
+// 1. Bet must be owned by the user and still placed
+// 2. Topic must still be published and not expired at closing time
+// 3. Prediction must be valid for the topic, as placing a bet
+// Then:
+// let bet resource revise the prediction, the replaced one is kept in its revisions
+func (gw *Gateway) ChangeBet(ctx context.Context, email, id string, cb *command.ChangeBet) (*dto.Bet, error) {
+	_, topic, err := gw.ownBet(ctx, email, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyPrediction(topic, cb.Prediction, cb.Probability); err != nil {
+		return nil, err
+	}
+
+	bet := &dto.Bet{}
+	return bet, gw.doReq(ctx, &req{
+		res:   "bets",
+		mtd:   "POST",
+		url:   gw.conf.GetOneBetURL(id, "revisions"),
+		pay:   &dto.Wrapper{Data: cb},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(bet),
+	})
+}
+
+// CancelBet ...
+// Verification:
+// 1. Bet must be owned by the user and still placed
+// 2. Topic must still be published and not expired at closing time
+// Then:
+// let bet resource cancel the bet, stake is refunded minus cancellation fee
+func (gw *Gateway) CancelBet(ctx context.Context, email, id string) (*dto.Bet, error) {
+	if _, _, err := gw.ownBet(ctx, email, id); err != nil {
+		return nil, err
+	}
+
+	bet := &dto.Bet{}
+	return bet, gw.doReq(ctx, &req{
+		res:   "bets",
+		mtd:   "POST",
+		url:   gw.conf.GetOneBetURL(id, "cancellation"),
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(bet),
+	})
+}
+
+// ownBet of the user on an open topic, only placed bet can be changed
+func (gw *Gateway) ownBet(ctx context.Context, email, id string) (*dto.Bet, *dto.Topic, error) {
+	if id == "" {
+		return nil, nil, exception.New(http.StatusBadRequest, "Bet can't be empty")
+	}
+
+	user, err := gw.MyProfile(ctx, email)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bet := &dto.Bet{}
+	if err := gw.doReq(ctx, &req{
+		res:   "bets",
+		mtd:   "GET",
+		url:   gw.conf.GetOneBetURL(id),
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(bet),
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	// other's bet is hidden as if it doesn't exist
+	if bet.Owner != user.ID {
+		return nil, nil, exception.New(http.StatusNotFound, "Bet with ID: %s, is not found", id)
+	}
+	if bet.State != string(betDao.BetStates.Placed()) {
+		return nil, nil, exception.New(http.StatusConflict, "Bet is already %s and can't be changed", bet.State)
+	}
+
+	topic := &dto.Topic{}
+	if err := gw.doReq(ctx, &req{
+		res:   "topics",
+		mtd:   "GET",
+		url:   gw.conf.GetTopicURL(bet.TopicID),
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(topic),
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return bet, topic, verifyOpen(topic)
+}
+
+// verifyOpen topic, published and not expired at closing time
+func verifyOpen(topic *dto.Topic) error {
 	if topic.State != "published" {
 		switch topic.State {
 		case "closed", "answered":
-			return nil, exception.New(http.StatusBadRequest, "Topic is already closed")
+			return exception.New(http.StatusBadRequest, "Topic is already closed")
+		case "voided":
+			return exception.New(http.StatusConflict, "Topic was voided")
 		case "draft":
-			return nil, exception.New(http.StatusBadRequest, "Topic is not published yet")
+			return exception.New(http.StatusBadRequest, "Topic is not published yet")
 		default:
-			return nil, exception.New(http.StatusBadRequest, "Topic state is unknown")
+			return exception.New(http.StatusBadRequest, "Topic state is unknown")
 		}
 	}
 
 	// now exceed closing time
 	if time.Now().Unix() > topic.ClosingAt.Unix() {
-		return nil, exception.New(http.StatusBadRequest, "Topic is already closed")
+		return exception.New(http.StatusBadRequest, "Topic is already closed")
 	}
 
-	// verify bet
-	if len(bets) > 0 {
-		return nil, exception.New(http.StatusConflict, "Bet already exists")
+	return nil
+}
+
+// verifyPrediction of a bet on the topic
+func verifyPrediction(topic *dto.Topic, prediction string, probability *float64) error {
+	// verify prediction of multiple-choice topic
+	if len(topic.Options) > 0 && !topic.HasOption(prediction) {
+		return exception.New(http.StatusBadRequest, "Prediction must be one of topic options: %s", strings.Join(topic.OptionIDs(), ", "))
 	}
 
-	// create a bet
-	user := users[0]
-	return gw.createBet(ctx, &dto.Bet{
-		Owner:      user.ID,
-		TopicID:    pb.Topic,
-		Prediction: pb.Prediction,
-		Reputation: pb.Stake,
-	})
+	// verify prediction of number or date topic, probability of probability topic
+	kind, _ := topicDao.PredictionKinds.Parse(topic.Kind)
+	if kind == topicDao.PredictionKinds.Probability() {
+		if probability == nil || *probability < 0 || *probability > 100 {
+			return exception.New(http.StatusBadRequest, "Probability must be between 0 and 100")
+		}
+	} else if probability != nil {
+		return exception.New(http.StatusBadRequest, "Probability is only accepted by probability topic")
+	} else if kind.Scored() {
+		if _, err := kind.Value(prediction); err != nil {
+			return exception.New(http.StatusBadRequest, "Prediction must be a %s, got %s", kind, prediction)
+		}
+	}
+
+	return nil
 }
 
 // MyProfile fetch currently logged in user profile based on email
@@ -214,6 +378,9 @@ func (gw *Gateway) doReq(ctx context.Context, req *req) error {
 	var call func(string) (*resty.Response, error)
 
 	api := gw.rc.R().SetContext(ctx)
+	if email, ok := ctx.Value(global.Context.Email()).(string); ok {
+		api.SetHeader(global.Header.ActingAs(), email)
+	}
 	switch req.mtd {
 	case http.MethodPost:
 		call = api.Post
@@ -0,0 +1,77 @@
+package command
+
+import "time"
+
+// DraftTopic command
+type DraftTopic struct {
+	ClosingAt *time.Time `json:"closing_at"`
+	PublishAt *time.Time `json:"publish_at"` // optional, publish the draft automatically
+	Banner    string     `json:"banner"`
+	Question  string     `json:"question"`
+	Context   string     `json:"context"`
+
+	PayoutMode string    `json:"payout_mode"` // flat (default) or parimutuel
+	Burn       *int      `json:"burn"`        // percentage of losers' pool taken by house in parimutuel
+	Weighted   bool      `json:"weighted"`    // scale reward by difficulty, flat payout only
+	EarlyBird  int       `json:"early_bird"`  // bonus percentage of earliest prediction, decays until closing, flat payout only
+	Options    []*Option `json:"options"`     // optional, makes it a multiple-choice topic
+	Kind       string    `json:"kind"`        // text (default), number, date or probability
+	Bands      []*Band   `json:"bands"`       // tolerance bands of number or date, exact match if empty
+	Rule       string    `json:"rule"`        // scoring rule of probability, brier (default) or log
+	Matcher    string    `json:"matcher"`     // matcher of free text answer, exact (default), normalized, numeric, regex or edit
+	MaxEdits   *int      `json:"max_edits"`   // tolerated edit distance of edit matcher
+}
+
+// EditTopic command of a draft, empty field is left unchanged
+type EditTopic struct {
+	ClosingAt *time.Time `json:"closing_at,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	Banner    string     `json:"banner,omitempty"`
+	Question  string     `json:"question,omitempty"`
+	Context   string     `json:"context,omitempty"`
+
+	PayoutMode string    `json:"payout_mode,omitempty"`
+	Burn       *int      `json:"burn,omitempty"`
+	Weighted   *bool     `json:"weighted,omitempty"`
+	EarlyBird  *int      `json:"early_bird,omitempty"`
+	Options    []*Option `json:"options,omitempty"` // replaces all options
+	Kind       string    `json:"kind,omitempty"`
+	Bands      []*Band   `json:"bands,omitempty"` // replaces all bands
+	Rule       string    `json:"rule,omitempty"`
+	Matcher    string    `json:"matcher,omitempty"`
+	MaxEdits   *int      `json:"max_edits,omitempty"`
+}
+
+// Option of multiple-choice topic, ID is generated from its position if empty
+type Option struct {
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+}
+
+// Answer a topic
+type Answer struct {
+	Topic      string   `json:"topic"`
+	Answer     string   `json:"answer"`
+	Variations []string `json:"variations"`
+}
+
+// Correct answer of a topic
+type Correct struct {
+	Topic      string   `json:"topic"`
+	Answer     string   `json:"answer"`
+	Variations []string `json:"variations"`
+	Reason     string   `json:"reason"`
+}
+
+// Void a topic
+type Void struct {
+	Topic  string `json:"topic"`
+	Reason string `json:"reason"`
+}
+
+// Band of tolerance, prediction within distance of the answer is rewarded a percentage of its stake
+type Band struct {
+	Within float64 `json:"within"`
+	Reward int     `json:"reward"` // between -100 and 100
+}
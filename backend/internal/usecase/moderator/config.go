@@ -0,0 +1,56 @@
+package moderator
+
+import (
+	"net/url"
+	"path"
+)
+
+// ConfigConst ...
+type ConfigConst struct {
+	ServiceKey string // shared secret to call internal resources
+}
+
+// ConfigURL ...
+type ConfigURL struct {
+	Topic      string
+	Answer     string // platform answer endpoint
+	Correction string // platform correction endpoint
+	Void       string // platform void endpoint
+	DryRun     string // platform matchers dry run endpoint
+	Preview    string // platform answer preview endpoint
+}
+
+// Config ...
+type Config struct {
+	Const *ConfigConst
+	URL   *ConfigURL
+}
+
+// GetTopicURL based on topic id
+func (conf *Config) GetTopicURL(id string) string {
+	uri, _ := url.Parse(conf.URL.Topic)
+	uri.Path = path.Join(uri.Path, id)
+	return uri.String()
+}
+
+// FindTopicURL ...
+func (conf *Config) FindTopicURL(query url.Values) string {
+	uri, _ := url.Parse(conf.URL.Topic)
+	uri.RawQuery = query.Encode()
+
+	return uri.String()
+}
+
+// GetTransitionURL of topic state based on topic id
+func (conf *Config) GetTransitionURL(id string) string {
+	uri, _ := url.Parse(conf.URL.Topic)
+	uri.Path = path.Join(uri.Path, id, "transitions")
+	return uri.String()
+}
+
+// GetAnswerURL of settlement job
+func (conf *Config) GetAnswerURL(job string) string {
+	uri, _ := url.Parse(conf.URL.Answer)
+	uri.Path = path.Join(uri.Path, job)
+	return uri.String()
+}
@@ -0,0 +1,101 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/di-collective/ditebak/backend/internal/usecase/moderator/command"
+)
+
+// Topic dto
+type Topic struct {
+	ID        string     `json:"id,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	ClosingAt *time.Time `json:"closing_at,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	Author    string     `json:"author,omitempty"` // who drafted the topic (email)
+	Banner    string     `json:"banner,omitempty"`
+	Question  string     `json:"question,omitempty"`
+	Answer    string     `json:"answer,omitempty"`
+	Context   string     `json:"context,omitempty"`
+	State     string     `json:"state,omitempty"`
+
+	PayoutMode string            `json:"payout_mode,omitempty"`
+	Burn       *int              `json:"burn,omitempty"`
+	Weighted   bool              `json:"weighted,omitempty"`
+	EarlyBird  int               `json:"early_bird,omitempty"`
+	Difficulty *int              `json:"difficulty,omitempty"` // percentage of bettors who got it wrong, once answered
+	Options    []*command.Option `json:"options,omitempty"`
+	Kind       string            `json:"kind,omitempty"`
+	Bands      []*command.Band   `json:"bands,omitempty"`
+	Rule       string            `json:"rule,omitempty"`
+	Matcher    string            `json:"matcher,omitempty"`
+	MaxEdits   *int              `json:"max_edits,omitempty"`
+}
+
+// Answered statistics
+type Answered struct {
+	Lost   int   `json:"lost"`
+	Won    int   `json:"won"`
+	Void   int   `json:"void"`
+	Total  int   `json:"total"`
+	Burned int64 `json:"burned"`
+}
+
+// Job of settlement, answered, corrected or voided topic is settled in background
+type Job struct {
+	ID         string     `json:"id"`
+	CreatedAt  *time.Time `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Kind       string     `json:"kind"`
+	Reason     string     `json:"reason,omitempty"`
+	Difficulty int        `json:"difficulty"`
+	State      string     `json:"state"`
+	Error      string     `json:"error,omitempty"`
+	Processed  int        `json:"processed"`
+	Stat       *Answered  `json:"stat"`
+}
+
+// Payout of a bet
+type Payout struct {
+	Bet   string `json:"bet"`
+	User  string `json:"user"`
+	State string `json:"state"`
+	Stake int64  `json:"stake"`
+	Net   int64  `json:"net"`
+}
+
+// Preview of answering a topic, nothing is settled
+type Preview struct {
+	Topic string    `json:"topic"`
+	Kind  string    `json:"kind"`
+	Stat  *Answered `json:"stat"`
+
+	Difficulty int  `json:"difficulty"`
+	Weighted   bool `json:"weighted"`
+
+	Won    []*Payout `json:"won"`
+	Lost   []*Payout `json:"lost"`
+	Void   []*Payout `json:"void"`
+	Deltas []*struct {
+		User  string `json:"user"`
+		Delta int64  `json:"delta"`
+	} `json:"deltas"`
+}
+
+// Matched bets by a matcher, as if the topic is answered
+type Matched struct {
+	Matcher  string `json:"matcher"`
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+	Bets     []*struct {
+		ID         string `json:"id"`
+		Owner      string `json:"owner"`
+		Prediction string `json:"prediction"`
+	} `json:"bets"`
+}
+
+// Wrapper to data
+type Wrapper struct {
+	Data interface{} `json:"data"`
+}
@@ -0,0 +1,592 @@
+package moderator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	resty "github.com/go-resty/resty/v2"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/di-collective/ditebak/backend/internal/domain/settlement"
+	topicDao "github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
+	"github.com/di-collective/ditebak/backend/internal/usecase/moderator/command"
+	"github.com/di-collective/ditebak/backend/internal/usecase/moderator/dto"
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/global"
+)
+
+type req struct {
+	mtd   string
+	res   string
+	url   string
+	pay   interface{}
+	err   func(res *resty.Response) error
+	parse func([]byte) error
+	match string  // If-Match of the request, ETag of the version being written
+	etag  *string // ETag of the response, if any
+}
+
+// Gateway ...
+type Gateway struct {
+	conf *Config
+	rc   *resty.Client
+}
+
+// New instance of moderator's gateway
+func New(conf *Config) *Gateway {
+	gw := &Gateway{
+		conf: conf,
+		rc:   resty.New().EnableTrace().SetHeader(global.Header.ServiceKey(), conf.Const.ServiceKey),
+	}
+
+	return gw
+}
+
+// Forward request to API, returns its body and ETag
+func (gw *Gateway) Forward(ctx context.Context, uri string) ([]byte, string, error) {
+	log.Traceln("Tunneling to:", uri)
+
+	var result []byte
+	var etag string
+	err := gw.doReq(ctx, &req{
+		mtd: "GET",
+		url: uri,
+		err: defaultResponseHandler,
+		parse: func(b []byte) error {
+			result = b
+			return nil
+		},
+		etag: &etag,
+	})
+	return result, etag, err
+}
+
+// DraftTopic ...
+// Verification:
+// 1. Question can't be empty
+// 2. Closing time must be in the future
+// 3. Publishing time, if scheduled, must be before closing time
+// 4. Payout mode must be known, burn and early bird bonus must be between 0 and 100
+// 5. Only flat payout can be weighted by difficulty or give early bird bonus
+// 6. Options, if declared, must be at least 2 with unique ID and non empty label
+// 7. Kind must be known, number, date or probability has neither options nor parimutuel payout
+// 8. Matcher, if declared, must be known and only for free text topic
+// Then:
+// create the topic as draft, authored by the moderator
+func (gw *Gateway) DraftTopic(ctx context.Context, email string, dt *command.DraftTopic) (*dto.Topic, error) {
+	if strings.TrimSpace(dt.Question) == "" {
+		return nil, exception.New(http.StatusBadRequest, "Question can't be empty")
+	}
+	if dt.ClosingAt == nil || dt.ClosingAt.Before(time.Now()) {
+		return nil, exception.New(http.StatusBadRequest, "Closing time must be in the future")
+	}
+	if dt.PublishAt != nil && !dt.PublishAt.Before(*dt.ClosingAt) {
+		return nil, exception.New(http.StatusBadRequest, "Publishing time must be before closing time")
+	}
+	if err := verifyPayout(dt.PayoutMode, dt.Burn, dt.Weighted, dt.EarlyBird); err != nil {
+		return nil, err
+	}
+	if err := verifyOptions(dt.Options); err != nil {
+		return nil, err
+	}
+	if err := verifyScoring(dt.Kind, dt.Rule, dt.Bands, dt.Options, dt.PayoutMode); err != nil {
+		return nil, err
+	}
+	if err := verifyMatcher(dt.Matcher, dt.MaxEdits, dt.Kind, dt.Options); err != nil {
+		return nil, err
+	}
+
+	topic := &dto.Topic{
+		ClosingAt: dt.ClosingAt,
+		PublishAt: dt.PublishAt,
+		Author:    email,
+		Banner:    dt.Banner,
+		Question:  dt.Question,
+		Context:   dt.Context,
+
+		PayoutMode: dt.PayoutMode,
+		Burn:       dt.Burn,
+		Weighted:   dt.Weighted,
+		EarlyBird:  dt.EarlyBird,
+		Options:    dt.Options,
+		Kind:       dt.Kind,
+		Bands:      dt.Bands,
+		Rule:       dt.Rule,
+		Matcher:    dt.Matcher,
+		MaxEdits:   dt.MaxEdits,
+	}
+	return topic, gw.doReq(ctx, &req{
+		mtd:   "POST",
+		res:   "topics",
+		url:   gw.conf.URL.Topic,
+		pay:   &dto.Wrapper{Data: topic},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(topic),
+	})
+}
+
+// EditTopic ...
+// Verification:
+// 1. Topic must exists
+//   - state is draft, bets are placed knowing the rest once published
+//   - of the version in If-Match, if any
+//
+// 2. Closing time, if changed, must be in the future
+// 3. Publishing time, if changed, must be before closing time
+// 4. Payout, options, scoring and matcher, if changed, must be valid
+// Then:
+// update the topic if unchanged since it was verified, returns its new ETag
+func (gw *Gateway) EditTopic(ctx context.Context, id, match string, et *command.EditTopic) (*dto.Topic, string, error) {
+	if et.ClosingAt != nil && et.ClosingAt.Before(time.Now()) {
+		return nil, "", exception.New(http.StatusBadRequest, "Closing time must be in the future")
+	}
+
+	topic, etag, err := gw.getTaggedTopic(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if match != "" && match != "*" && strings.TrimPrefix(match, "W/") != strings.TrimPrefix(etag, "W/") {
+		return nil, "", exception.New(http.StatusPreconditionFailed, "Topic has been modified, it is now %s", etag)
+	}
+
+	if topic.State != string(topicDao.TopicStates.Draft()) {
+		return nil, "", exception.New(http.StatusConflict, "Topic is already %s and can't be edited", topic.State)
+	}
+
+	closingAt := topic.ClosingAt
+	if et.ClosingAt != nil {
+		closingAt = et.ClosingAt
+	}
+	if et.PublishAt != nil && closingAt != nil && !et.PublishAt.Before(*closingAt) {
+		return nil, "", exception.New(http.StatusBadRequest, "Publishing time must be before closing time")
+	}
+
+	if et.PayoutMode != "" || et.Burn != nil || et.Weighted != nil || et.EarlyBird != nil || et.Options != nil || et.Kind != "" ||
+		et.Bands != nil || et.Rule != "" || et.Matcher != "" || et.MaxEdits != nil {
+		if err := verifyOptions(et.Options); err != nil {
+			return nil, "", err
+		}
+
+		// verify payout and scoring as a whole, unchanged field keeps current value
+		kind, rule, bands, options, mode, weighted := topic.Kind, topic.Rule, topic.Bands, topic.Options, topic.PayoutMode, topic.Weighted
+		if et.Kind != "" {
+			kind = et.Kind
+		}
+		if et.Rule != "" {
+			rule = et.Rule
+		}
+		if et.Bands != nil {
+			bands = et.Bands
+		}
+		if et.Options != nil {
+			options = et.Options
+		}
+		if et.PayoutMode != "" {
+			mode = et.PayoutMode
+		}
+		if et.Weighted != nil {
+			weighted = *et.Weighted
+		}
+		earlyBird := topic.EarlyBird
+		if et.EarlyBird != nil {
+			earlyBird = *et.EarlyBird
+		}
+		if err := verifyPayout(mode, et.Burn, weighted, earlyBird); err != nil {
+			return nil, "", err
+		}
+		if err := verifyScoring(kind, rule, bands, options, mode); err != nil {
+			return nil, "", err
+		}
+
+		matcher, maxEdits := topic.Matcher, topic.MaxEdits
+		if et.Matcher != "" {
+			matcher = et.Matcher
+		}
+		if et.MaxEdits != nil {
+			maxEdits = et.MaxEdits
+		}
+		if err := verifyMatcher(matcher, maxEdits, kind, options); err != nil {
+			return nil, "", err
+		}
+	}
+
+	etag, err = gw.patchTopic(ctx, id, etag, et, topic)
+	return topic, etag, err
+}
+
+// PublishTopic ...
+// Verification:
+// 1. Topic must exists
+//   - not expired at closing time
+//
+// Then:
+// move the topic from draft to published
+func (gw *Gateway) PublishTopic(ctx context.Context, id string) (*dto.Topic, error) {
+	topic, err := gw.getTopic(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if topic.ClosingAt == nil || topic.ClosingAt.Before(time.Now()) {
+		return nil, exception.New(http.StatusBadRequest, "Closing time must be in the future")
+	}
+
+	return gw.transitTopic(ctx, id, topicDao.TopicStates.Published())
+}
+
+// CloseTopic move the topic from published to closed
+func (gw *Gateway) CloseTopic(ctx context.Context, id string) (*dto.Topic, error) {
+	return gw.transitTopic(ctx, id, topicDao.TopicStates.Closed())
+}
+
+// AnswerTopic ...
+// Verification:
+// 1. Answer can't be empty
+// Then:
+// let platform settle the topic in background, which only accepts closed topic
+func (gw *Gateway) AnswerTopic(ctx context.Context, ans *command.Answer) (*dto.Job, error) {
+	if strings.TrimSpace(ans.Answer) == "" {
+		return nil, exception.New(http.StatusBadRequest, "Answer can't be empty")
+	}
+
+	job := &dto.Job{}
+	return job, gw.doReq(ctx, &req{
+		mtd:   "POST",
+		res:   "answers",
+		url:   gw.conf.URL.Answer,
+		pay:   &dto.Wrapper{Data: ans},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(job),
+	})
+}
+
+// PreviewAnswer ...
+// Verification:
+// 1. Answer can't be empty
+// Then:
+// let platform compute the would-be stat, winning and losing bets and reputation delta of each user, nothing is settled
+func (gw *Gateway) PreviewAnswer(ctx context.Context, ans *command.Answer) (*dto.Preview, error) {
+	if strings.TrimSpace(ans.Answer) == "" {
+		return nil, exception.New(http.StatusBadRequest, "Answer can't be empty")
+	}
+
+	preview := &dto.Preview{}
+	return preview, gw.doReq(ctx, &req{
+		mtd:   "POST",
+		res:   "previews",
+		url:   gw.conf.URL.Preview,
+		pay:   &dto.Wrapper{Data: ans},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(preview),
+	})
+}
+
+// CorrectTopic ...
+// Verification:
+// 1. Answer and reason can't be empty
+// Then:
+// let platform settle the topic again in background, which only accepts answered topic
+func (gw *Gateway) CorrectTopic(ctx context.Context, cor *command.Correct) (*dto.Job, error) {
+	if strings.TrimSpace(cor.Answer) == "" {
+		return nil, exception.New(http.StatusBadRequest, "Answer can't be empty")
+	}
+	if strings.TrimSpace(cor.Reason) == "" {
+		return nil, exception.New(http.StatusBadRequest, "Reason can't be empty")
+	}
+
+	job := &dto.Job{}
+	return job, gw.doReq(ctx, &req{
+		mtd:   "POST",
+		res:   "corrections",
+		url:   gw.conf.URL.Correction,
+		pay:   &dto.Wrapper{Data: cor},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(job),
+	})
+}
+
+// VoidTopic ...
+// Verification:
+// 1. Reason can't be empty
+// Then:
+// let platform refund every bet in background, which only accepts closed or answered topic
+func (gw *Gateway) VoidTopic(ctx context.Context, vd *command.Void) (*dto.Job, error) {
+	if strings.TrimSpace(vd.Reason) == "" {
+		return nil, exception.New(http.StatusBadRequest, "Reason can't be empty")
+	}
+
+	job := &dto.Job{}
+	return job, gw.doReq(ctx, &req{
+		mtd:   "POST",
+		res:   "voids",
+		url:   gw.conf.URL.Void,
+		pay:   &dto.Wrapper{Data: vd},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(job),
+	})
+}
+
+// DryRunTopic ...
+// Verification:
+// 1. Answer can't be empty
+// Then:
+// let platform match bets of the topic against the answer with every matcher, nothing is settled
+func (gw *Gateway) DryRunTopic(ctx context.Context, ans *command.Answer) ([]*dto.Matched, error) {
+	if strings.TrimSpace(ans.Answer) == "" {
+		return nil, exception.New(http.StatusBadRequest, "Answer can't be empty")
+	}
+
+	result := []*dto.Matched{}
+	return result, gw.doReq(ctx, &req{
+		mtd:   "POST",
+		res:   "matchers",
+		url:   gw.conf.URL.DryRun,
+		pay:   &dto.Wrapper{Data: ans},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(&result),
+	})
+}
+
+// verifyPayout mode, burn percentage, weighting and early bird bonus
+func verifyPayout(mode string, burn *int, weighted bool, earlyBird int) error {
+	m, ok := topicDao.PayoutModes.Parse(mode)
+	if !ok {
+		return exception.New(http.StatusBadRequest, "Unknown payout mode: %s", mode)
+	}
+	if weighted && m == topicDao.PayoutModes.Parimutuel() {
+		return exception.New(http.StatusBadRequest, "Parimutuel payout is already weighted by its pool")
+	}
+	if earlyBird < 0 || earlyBird > 100 {
+		return exception.New(http.StatusBadRequest, "Early bird bonus must be between 0 and 100")
+	}
+	if earlyBird > 0 && m == topicDao.PayoutModes.Parimutuel() {
+		return exception.New(http.StatusBadRequest, "Parimutuel payout can't give early bird bonus, its pool is fixed")
+	}
+	if burn != nil && (*burn < 0 || *burn > 100) {
+		return exception.New(http.StatusBadRequest, "Burn must be between 0 and 100")
+	}
+
+	return nil
+}
+
+// verifyScoring of prediction kind
+func verifyScoring(kind, rule string, bands []*command.Band, options []*command.Option, mode string) error {
+	k, ok := topicDao.PredictionKinds.Parse(kind)
+	if !ok {
+		return exception.New(http.StatusBadRequest, "Unknown prediction kind: %s", kind)
+	}
+
+	probability := k == topicDao.PredictionKinds.Probability()
+	if _, ok := topicDao.ScoringRules.Parse(rule); !ok || (rule != "" && !probability) {
+		return exception.New(http.StatusBadRequest, "Scoring rule must be brier or log, for probability topic only")
+	}
+	if !k.Scored() && len(bands) > 0 {
+		return exception.New(http.StatusBadRequest, "Only number or date topic can declare bands")
+	}
+	if !k.Scored() && !probability {
+		return nil
+	}
+
+	if len(options) > 0 {
+		return exception.New(http.StatusBadRequest, "%s topic can't declare options", k)
+	}
+	if m, _ := topicDao.PayoutModes.Parse(mode); m == topicDao.PayoutModes.Parimutuel() {
+		return exception.New(http.StatusBadRequest, "%s topic can't use parimutuel payout", k)
+	}
+	for i, band := range bands {
+		if band == nil || band.Within < 0 {
+			return exception.New(http.StatusBadRequest, "Distance of band %d can't be negative", i+1)
+		}
+		if band.Reward < -100 || band.Reward > 100 {
+			return exception.New(http.StatusBadRequest, "Reward of band %d must be between -100 and 100", i+1)
+		}
+	}
+
+	return nil
+}
+
+// verifyMatcher of free text topic, empty matcher is exact
+func verifyMatcher(matcher string, maxEdits *int, kind string, options []*command.Option) error {
+	if maxEdits != nil && *maxEdits < 0 {
+		return exception.New(http.StatusBadRequest, "Max edits can't be negative")
+	}
+	if matcher == "" && (maxEdits == nil || *maxEdits == 0) {
+		return nil
+	}
+
+	if _, ok := settlement.NewMatcher(matcher, 0); !ok {
+		return exception.New(http.StatusBadRequest, "Unknown matcher: %s, must be one of %s",
+			matcher, strings.Join(settlement.MatcherNames(), ", "))
+	}
+	if k, _ := topicDao.PredictionKinds.Parse(kind); k != topicDao.PredictionKinds.Text() || len(options) > 0 {
+		return exception.New(http.StatusBadRequest, "Matcher is for free text topic only")
+	}
+
+	return nil
+}
+
+// verifyOptions of multiple-choice topic, empty ID is generated from its position
+func verifyOptions(options []*command.Option) error {
+	if len(options) == 0 {
+		return nil
+	}
+	if len(options) < 2 {
+		return exception.New(http.StatusBadRequest, "Multiple-choice topic needs at least 2 options")
+	}
+
+	seen := map[string]bool{}
+	for i, opt := range options {
+		if opt == nil || strings.TrimSpace(opt.Label) == "" {
+			return exception.New(http.StatusBadRequest, "Label of option %d can't be empty", i+1)
+		}
+
+		opt.ID = strings.TrimSpace(opt.ID)
+		if opt.ID == "" {
+			opt.ID = strconv.Itoa(i + 1)
+		}
+		if seen[opt.ID] {
+			return exception.New(http.StatusBadRequest, "Option ID %s is declared more than once", opt.ID)
+		}
+		seen[opt.ID] = true
+	}
+
+	return nil
+}
+
+func (gw *Gateway) getTopic(ctx context.Context, id string) (*dto.Topic, error) {
+	topic, _, err := gw.getTaggedTopic(ctx, id)
+	return topic, err
+}
+
+// getTaggedTopic along with ETag of its version
+func (gw *Gateway) getTaggedTopic(ctx context.Context, id string) (*dto.Topic, string, error) {
+	if id == "" {
+		return nil, "", exception.New(http.StatusBadRequest, "Topic can't be empty")
+	}
+
+	topic := &dto.Topic{}
+	var etag string
+	err := gw.doReq(ctx, &req{
+		mtd:   "GET",
+		res:   "topics",
+		url:   gw.conf.GetTopicURL(id),
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(topic),
+		etag:  &etag,
+	})
+	return topic, etag, err
+}
+
+// transitTopic state, illegal transition is rejected by topic service
+func (gw *Gateway) transitTopic(ctx context.Context, id string, state interface{}) (*dto.Topic, error) {
+	if id == "" {
+		return nil, exception.New(http.StatusBadRequest, "Topic can't be empty")
+	}
+
+	topic := &dto.Topic{}
+	return topic, gw.doReq(ctx, &req{
+		mtd: "POST",
+		res: "topics",
+		url: gw.conf.GetTransitionURL(id),
+		pay: &dto.Wrapper{Data: &map[string]interface{}{
+			"state": state,
+		}},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(topic),
+	})
+}
+
+// patchTopic of the version tagged by match and parse the updated fields into topic, returns its new ETag
+func (gw *Gateway) patchTopic(ctx context.Context, id, match string, payload interface{}, topic *dto.Topic) (string, error) {
+	var etag string
+	err := gw.doReq(ctx, &req{
+		mtd:   "PATCH",
+		res:   "topics",
+		url:   gw.conf.GetTopicURL(id),
+		pay:   &dto.Wrapper{Data: payload},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(topic),
+		match: match,
+		etag:  &etag,
+	})
+	return etag, err
+}
+
+func (gw *Gateway) doReq(ctx context.Context, req *req) error {
+	var call func(string) (*resty.Response, error)
+
+	api := gw.rc.R().SetContext(ctx)
+	if email, ok := ctx.Value(global.Context.Email()).(string); ok {
+		api.SetHeader(global.Header.ActingAs(), email)
+	}
+	switch req.mtd {
+	case http.MethodPost:
+		call = api.Post
+	case http.MethodPut:
+		call = api.Put
+	case http.MethodPatch:
+		call = api.Patch
+	default:
+		call = api.Get
+	}
+
+	if req.pay != nil {
+		api.SetBody(req.pay)
+	}
+	if req.match != "" {
+		api.SetHeader("If-Match", req.match)
+	}
+
+	res, err := call(req.url)
+	if err != nil {
+		return exception.New(http.StatusBadGateway, "Failed to [%s] to url: %s, err: %v", req.mtd, req.url, err)
+	}
+	if req.etag != nil {
+		*req.etag = res.Header().Get("ETag")
+	}
+
+	// delegated error condition
+	if err = req.err(res); err != nil {
+		return err
+	}
+
+	// delegate response parsing
+	if req.parse != nil {
+		if err = req.parse(res.Body()); err != nil {
+			return exception.New(http.StatusBadGateway, "Failed to parse response from url: %s, err: %s", req.url, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func defaultResponseHandler(res *resty.Response) error {
+	if res.IsError() {
+		switch res.StatusCode() {
+		case http.StatusRequestTimeout:
+			return exception.New(http.StatusGatewayTimeout, "Request timed out to [%s] url: %s", res.Request.Method, res.Request.URL)
+		case http.StatusBadRequest:
+			return exception.New(http.StatusBadRequest, "Invalid request to [%s] url: %s", res.Request.Method, res.Request.URL)
+		case http.StatusNotFound:
+			return exception.New(http.StatusNotFound, "Resource with [%s] url: %s, is not found", res.Request.Method, res.Request.URL)
+		}
+
+		return exception.New(res.StatusCode(), "Failed to [%s] to url: %s", res.Request.Method, res.Request.URL)
+	}
+
+	return nil
+}
+
+// @obj: please send a pointer to a struct
+func defaultResponseUnwrapper(obj interface{}) func(body []byte) error {
+	return func(body []byte) error {
+		wrapper := &dto.Wrapper{
+			Data: obj,
+		}
+		return json.Unmarshal(body, wrapper)
+	}
+}
@@ -1,7 +1,5 @@
 package command
 
-import "strings"
-
 // Answer a topic
 type Answer struct {
 	Topic      string   `json:"topic"`
@@ -9,20 +7,16 @@ type Answer struct {
 	Variations []string `json:"variations"`
 }
 
-// IsTrue check prediction against answer
-func (ans *Answer) IsTrue(prediction string) bool {
-	prediction = strings.TrimSpace(prediction)
-	answer := strings.TrimSpace(ans.Answer)
-	if prediction == answer {
-		return true
-	}
-
-	for _, variety := range ans.Variations {
-		variety = strings.TrimSpace(variety)
-		if prediction == variety {
-			return true
-		}
-	}
+// Correct answer of an answered topic
+type Correct struct {
+	Topic      string   `json:"topic"`
+	Answer     string   `json:"answer"`
+	Variations []string `json:"variations"`
+	Reason     string   `json:"reason"`
+}
 
-	return false
+// Void a closed or answered topic
+type Void struct {
+	Topic  string `json:"topic"`
+	Reason string `json:"reason"`
 }
@@ -20,6 +20,8 @@ type ConfigConst struct {
 	Prod            bool
 	SessionDuration time.Duration
 	AuthClient      AuthClient
+	ServiceKey      string // shared secret to call internal resources
+	StartingGrant   int64  // reputation granted to new user
 }
 
 // ConfigURL ...
@@ -28,6 +30,11 @@ type ConfigURL struct {
 	User  string
 	Topic string
 	Bet   string
+
+	Settlement string
+	Ledger     string
+	Matcher    string
+	Preview    string
 }
 
 // Config ...
@@ -50,6 +57,20 @@ func (conf *Config) GetTopicURL(id string) string {
 	return uri.String()
 }
 
+// GetTransitionURL of topic state based on topic id
+func (conf *Config) GetTransitionURL(id string) string {
+	uri, _ := url.Parse(conf.URL.Topic)
+	uri.Path = path.Join(uri.Path, id, "transitions")
+	return uri.String()
+}
+
+// GetSettlementURL of a job, with optional action
+func (conf *Config) GetSettlementURL(job string, action ...string) string {
+	uri, _ := url.Parse(conf.URL.Settlement)
+	uri.Path = path.Join(append([]string{uri.Path, job}, action...)...)
+	return uri.String()
+}
+
 // GetBetURL based on topic and owner
 func (conf *Config) GetBetURL(topic, owner string) string {
 	uri, _ := url.Parse(conf.URL.Bet)
@@ -72,3 +93,10 @@ func (conf *Config) GetMyBetURL(owner string) string {
 	uri.RawQuery = q.Encode()
 	return uri.String()
 }
+
+// GetDryRunURL of matchers
+func (conf *Config) GetDryRunURL() string {
+	uri, _ := url.Parse(conf.URL.Matcher)
+	uri.Path = path.Join(uri.Path, "dry-run")
+	return uri.String()
+}
@@ -11,6 +11,7 @@ type Topic struct {
 	ID        string     `json:"id,omitempty"`
 	CreatedAt *time.Time `json:"created_at"`
 	ClosingAt *time.Time `json:"closing_at"`
+	PublishAt *time.Time `json:"publish_at"`
 	Banner    string     `json:"banner"`
 	Question  string     `json:"question"`
 	Answer    string     `json:"answer"`
@@ -1,10 +1,108 @@
 package dto
 
+import "time"
+
 // Answered statistics
 type Answered struct {
-	Lost  int `json:"lost"`
-	Won   int `json:"won"`
-	Total int `json:"total"`
+	Lost   int   `json:"lost"`
+	Won    int   `json:"won"`
+	Void   int   `json:"void"`
+	Total  int   `json:"total"`
+	Burned int64 `json:"burned"`
+}
+
+// Entry of reputation ledger
+type Entry struct {
+	ID      string `json:"id"`
+	User    string `json:"user"`
+	Delta   int64  `json:"delta"`
+	Balance int64  `json:"balance"` // reputation of user after the entry
+	Reason  string `json:"reason"`
+}
+
+// Job of settlement
+type Job struct {
+	ID         string     `json:"id"`
+	CreatedAt  *time.Time `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	By         string     `json:"by"`
+	Topic      string     `json:"topic"`
+	Kind       string     `json:"kind"`
+	Previous   string     `json:"previous,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	State      string     `json:"state"`
+	Error      string     `json:"error,omitempty"`
+	Processed  int        `json:"processed"`
+	Stat       *Answered  `json:"stat"`
+	Difficulty int        `json:"difficulty"`
+	Weighted   bool       `json:"weighted"`
+
+	Pool *struct {
+		Burn    int   `json:"burn"`
+		Winners int64 `json:"winners"`
+		Losers  int64 `json:"losers"`
+	} `json:"pool,omitempty"`
+
+	Payouts []*struct {
+		Bet   string `json:"bet"`
+		User  string `json:"user"`
+		State string `json:"state"`
+		Stake int64  `json:"stake"`
+		Net   int64  `json:"net"`
+	} `json:"payouts"`
+
+	DeadLetters []*struct {
+		Bet      string     `json:"bet"`
+		Error    string     `json:"error"`
+		Attempts int        `json:"attempts"`
+		At       *time.Time `json:"at"`
+	} `json:"dead_letters"`
+}
+
+// Payout of a bet
+type Payout struct {
+	Bet   string `json:"bet"`
+	User  string `json:"user"`
+	State string `json:"state"`
+	Stake int64  `json:"stake"`
+	Net   int64  `json:"net"`
+}
+
+// Preview of settlement, nothing is written
+type Preview struct {
+	Topic string    `json:"topic"`
+	Kind  string    `json:"kind"`
+	Stat  *Answered `json:"stat"`
+
+	Difficulty int  `json:"difficulty"`
+	Weighted   bool `json:"weighted"`
+
+	Won    []*Payout `json:"won"`
+	Lost   []*Payout `json:"lost"`
+	Void   []*Payout `json:"void"`
+	Deltas []*struct {
+		User  string `json:"user"`
+		Delta int64  `json:"delta"`
+	} `json:"deltas"`
+
+	Pool *struct {
+		Burn    int   `json:"burn"`
+		Winners int64 `json:"winners"`
+		Losers  int64 `json:"losers"`
+	} `json:"pool,omitempty"`
+}
+
+// Matched bets by a matcher
+type Matched struct {
+	Matcher  string `json:"matcher"`
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+	Bets     []*struct {
+		ID         string `json:"id"`
+		Owner      string `json:"owner"`
+		Prediction string `json:"prediction"`
+	} `json:"bets"`
 }
 
 // Wrapper to data
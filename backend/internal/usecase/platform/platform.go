@@ -3,7 +3,6 @@ package platform
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"net/url"
 	"path"
@@ -11,12 +10,10 @@ import (
 	resty "github.com/go-resty/resty/v2"
 	log "github.com/sirupsen/logrus"
 
-	"github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
-	betDao "github.com/di-collective/ditebak/backend/internal/domain/bet/dao"
-	topicDao "github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
 	"github.com/di-collective/ditebak/backend/internal/usecase/platform/command"
 	"github.com/di-collective/ditebak/backend/internal/usecase/platform/dto"
 	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/global"
 )
 
 type req struct {
@@ -39,7 +36,7 @@ type Gateway struct {
 func New(conf *Config) *Gateway {
 	gw := &Gateway{
 		conf: conf,
-		rc:   resty.New().EnableTrace().SetRetryCount(3),
+		rc:   resty.New().EnableTrace().SetRetryCount(3).SetHeader(global.Header.ServiceKey(), conf.Const.ServiceKey),
 		ac:   conf.Const.AuthClient,
 	}
 
@@ -48,8 +45,8 @@ func New(conf *Config) *Gateway {
 
 // Login to platform
 // Process:
-// 1. Create user & credential if new,
-// 2. Else, update credential
+// 1. Create user & credential if new, then grant starting reputation
+// 2. Else, update credential and grant starting reputation if it failed at sign up
 // 3. Verify the token
 // 4. Creat, store and return session string
 func (gw *Gateway) Login(ctx context.Context, login *command.Login) (string, *dto.User, error) {
@@ -69,10 +66,15 @@ func (gw *Gateway) Login(ctx context.Context, login *command.Login) (string, *dt
 			url:   gw.conf.URL.User,
 			pay:   &dto.Wrapper{Data: &user},
 			err:   defaultResponseHandler,
-			parse: nil,
+			parse: defaultResponseUnwrapper(user),
 		}); err != nil {
 			return "", nil, err
 		}
+
+		// grant starting reputation through the ledger
+		if err := gw.grant(ctx, user); err != nil {
+			return "", nil, err
+		}
 	} else {
 		// 1. Get existing user's credential
 		emailQuery := url.Values{
@@ -115,10 +117,21 @@ func (gw *Gateway) Login(ctx context.Context, login *command.Login) (string, *dt
 			return "", nil, err
 		}
 
-		if len(creds) < 1 {
+		if len(users) < 1 {
 			return "", nil, exception.New(http.StatusInternalServerError, "Failed to authenticate credential")
 		}
 		user = users[0]
+
+		// grant failed at sign up is retried, ledger grants once per user
+		granted, err := gw.granted(ctx, user)
+		if err != nil {
+			return "", nil, err
+		}
+		if !granted {
+			if err := gw.grant(ctx, user); err != nil {
+				return "", nil, err
+			}
+		}
 	}
 
 	// 2. Create / Update user's credential
@@ -144,17 +157,66 @@ func (gw *Gateway) Login(ctx context.Context, login *command.Login) (string, *dt
 	return session, user, err
 }
 
+// granted is true when the user already has starting reputation in the ledger
+func (gw *Gateway) granted(ctx context.Context, user *dto.User) (bool, error) {
+	if gw.conf.Const.StartingGrant <= 0 {
+		return true, nil
+	}
+
+	ledgerURL, _ := url.Parse(gw.conf.URL.Ledger)
+	ledgerURL.RawQuery = url.Values{
+		"page":   []string{"1"},
+		"size":   []string{"1"},
+		"user":   []string{user.ID},
+		"reason": []string{"grant"},
+	}.Encode()
+
+	entries := []*dto.Entry{}
+	err := gw.doReq(ctx, &req{
+		mtd:   "GET",
+		res:   "ledger",
+		url:   ledgerURL.String(),
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(&entries),
+	})
+	return len(entries) > 0, err
+}
+
+// grant starting reputation to a new user, granting twice returns the first grant
+func (gw *Gateway) grant(ctx context.Context, user *dto.User) error {
+	if gw.conf.Const.StartingGrant <= 0 {
+		return nil
+	}
+
+	entry := &dto.Entry{}
+	if err := gw.doReq(ctx, &req{
+		mtd: "POST",
+		res: "ledger",
+		url: gw.conf.URL.Ledger,
+		pay: &dto.Wrapper{Data: &map[string]interface{}{
+			"user":   user.ID,
+			"delta":  gw.conf.Const.StartingGrant,
+			"reason": "grant",
+		}},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(entry),
+	}); err != nil {
+		log.Errorf("Failed to grant starting reputation to user %s: %v", user.ID, err)
+		return err
+	}
+
+	// balance is read back from the ledger, the user may be granted concurrently
+	user.Reputation = entry.Balance
+	return nil
+}
+
 // Answer an existing topic
 // Verification:
-// 1. Topic must exists
-//    - already past closing time
+// 1. Topic and answer can't be empty
 // Then:
-// 1. Update topic as answered
-// 2. Find all bets with state = placed
-// 3. Flag wrong answer with "lost"
-// 4. Flag correct answer with "won"
-// 5. Reward karma!
-func (gw *Gateway) Answer(ctx context.Context, ans *command.Answer) (*dto.Answered, error) {
+// POST the answer to /settlements and return the job settling the topic in background,
+// which only accepts closed topic and pays the bets through the ledger
+func (gw *Gateway) Answer(ctx context.Context, ans *command.Answer) (*dto.Job, error) {
 	if ans.Topic == "" {
 		return nil, exception.New(http.StatusBadRequest, "Topic can't be empty")
 	}
@@ -162,148 +224,162 @@ func (gw *Gateway) Answer(ctx context.Context, ans *command.Answer) (*dto.Answer
 		return nil, exception.New(http.StatusBadRequest, "Answer can't be empty")
 	}
 
-	stat := &dto.Answered{
-		Lost:  0,
-		Won:   0,
-		Total: 0,
-	}
-
-	// 1. Update the topic as answered
+	job := &dto.Job{}
 	if err := gw.doReq(ctx, &req{
-		mtd: "PATCH",
-		res: "topics",
-		url: gw.conf.GetTopicURL(ans.Topic),
-		pay: &dto.Wrapper{Data: &map[string]interface{}{
-			"answer": ans.Answer,
-			"state":  topicDao.TopicStates.Answered(),
-		}},
+		mtd:   "POST",
+		res:   "settlements",
+		url:   gw.conf.URL.Settlement,
+		pay:   &dto.Wrapper{Data: ans},
 		err:   defaultResponseHandler,
-		parse: nil,
+		parse: defaultResponseUnwrapper(job),
 	}); err != nil {
-		log.Errorln("Failed to answer topic:", err)
+		log.Errorln("Failed to settle topic:", err)
 		return nil, err
 	}
 
-	// 2. Find all bets on a topic
-	bets := []*dto.Bet{}
-	betURL, _ := url.Parse(gw.conf.URL.Bet)
-	page, rpp := 0, "100"
-	for {
-		// partial paging
-		page++
-		rq := url.Values{
-			"topic": []string{ans.Topic},
-			"state": []string{string(betDao.BetStates.Placed())},
-			"page":  []string{fmt.Sprintf("%d", page)},
-			"size":  []string{rpp},
-		}
-		betURL.RawQuery = rq.Encode()
+	return job, nil
+}
 
-		partial := []*dto.Bet{}
-		if err := gw.doReq(ctx, &req{
-			mtd:   "GET",
-			res:   "bets",
-			url:   betURL.String(),
-			err:   defaultResponseHandler,
-			parse: defaultResponseUnwrapper(&partial),
-		}); err != nil {
-			log.Errorln("Failed to collect all bets:", err)
-			return nil, err
-		}
+// PreviewAnswer of a topic, nothing is written
+// Verification:
+// 1. Topic and answer can't be empty
+// Then:
+// let settlement resource compute the would-be stat, winning and losing bets and reputation delta of each user
+func (gw *Gateway) PreviewAnswer(ctx context.Context, ans *command.Answer) (*dto.Preview, error) {
+	if ans.Topic == "" {
+		return nil, exception.New(http.StatusBadRequest, "Topic can't be empty")
+	}
+	if ans.Answer == "" {
+		return nil, exception.New(http.StatusBadRequest, "Answer can't be empty")
+	}
 
-		// no more bets, break from loop
-		if len(partial) <= 0 {
-			break
-		}
+	preview := &dto.Preview{}
+	return preview, gw.doReq(ctx, &req{
+		mtd:   "POST",
+		res:   "previews",
+		url:   gw.conf.URL.Preview,
+		pay:   &dto.Wrapper{Data: ans},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(preview),
+	})
+}
 
-		bets = append(bets, partial...)
+// Correct answer of an answered topic
+// Verification:
+// 1. Topic, answer and reason can't be empty
+// Then:
+// let settlement resource create a job to settle the topic again in background
+// 1. Record the correction on topic, only answered topic can be corrected
+// 2. Revert karma of previous settlement
+// 3. Flag each bet with "won" or "lost" by the new answer, reward karma!
+func (gw *Gateway) Correct(ctx context.Context, cor *command.Correct) (*dto.Job, error) {
+	if cor.Topic == "" {
+		return nil, exception.New(http.StatusBadRequest, "Topic can't be empty")
 	}
-
-	// - Nobody bets
-	if len(bets) <= 0 {
-		return stat, nil
+	if cor.Answer == "" {
+		return nil, exception.New(http.StatusBadRequest, "Answer can't be empty")
+	}
+	if cor.Reason == "" {
+		return nil, exception.New(http.StatusBadRequest, "Reason can't be empty")
 	}
 
-	// 3. Find winner
-	betURL.RawQuery = "" // clean betURL query
-	stat.Total = len(bets)
-	betRequests := make([]*req, stat.Total)
-	for i, bet := range bets {
-		if ans.IsTrue(bet.Prediction) {
-			bet.State = string(betDao.BetStates.Won())
-			stat.Won++
-		} else {
-			bet.State = string(dao.BetStates.Lost())
-			stat.Lost++
-		}
+	return gw.settle(ctx, "correct", cor)
+}
 
-		betURL.Path = path.Join(betURL.Path, bet.ID)
-		betRequests[i] = &req{
-			mtd:   "PATCH",
-			res:   "bets",
-			url:   betURL.String(),
-			pay:   &dto.Wrapper{Data: bet},
-			err:   defaultResponseHandler,
-			parse: nil,
-		}
+// Void a closed or answered topic
+// Verification:
+// 1. Topic and reason can't be empty
+// Then:
+// let settlement resource create a job to void the topic in background
+// 1. Update topic as voided
+// 2. Revert karma of previous settlement, if any
+// 3. Flag every bet with "void", stakes are refunded
+func (gw *Gateway) Void(ctx context.Context, vd *command.Void) (*dto.Job, error) {
+	if vd.Topic == "" {
+		return nil, exception.New(http.StatusBadRequest, "Topic can't be empty")
+	}
+	if vd.Reason == "" {
+		return nil, exception.New(http.StatusBadRequest, "Reason can't be empty")
 	}
 
-	// 4. Update all bets to database
-	for _, req := range betRequests {
-		if err := gw.doReq(ctx, req); err != nil {
-			log.Errorf("Failed to update bet: %+v", req.pay)
-			continue
-		}
+	return gw.settle(ctx, "void", vd)
+}
+
+// settle a topic by submitting a command of the kind to settlement resource
+func (gw *Gateway) settle(ctx context.Context, kind string, cmd interface{}) (*dto.Job, error) {
+	pay := map[string]interface{}{}
+	raw, _ := json.Marshal(cmd)
+	if err := json.Unmarshal(raw, &pay); err != nil {
+		return nil, err
 	}
+	pay["kind"] = kind
 
-	// 5. Reward karma!
-	for _, bet := range bets {
-		user := &dto.User{}
-		userURL := gw.conf.GetUserURL(bet.Owner)
-		if err := gw.doReq(ctx, &req{
-			mtd:   "GET",
-			res:   "users",
-			url:   userURL,
-			err:   defaultResponseHandler,
-			parse: defaultResponseUnwrapper(user),
-		}); err != nil {
-			payload, _ := json.Marshal(bet)
-			log.Errorf("Failed to update user's karma, err: %s, payload: %s", err, string(payload))
-			continue
-		}
+	job := &dto.Job{}
+	if err := gw.doReq(ctx, &req{
+		mtd:   "POST",
+		res:   "settlements",
+		url:   gw.conf.URL.Settlement,
+		pay:   &dto.Wrapper{Data: &pay},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(job),
+	}); err != nil {
+		log.Errorf("Failed to %s topic: %v", kind, err)
+		return nil, err
+	}
 
-		rep := user.Reputation
-		switch bet.State {
-		case string(betDao.BetStates.Lost()):
-			rep -= bet.Reputation
-		case string(betDao.BetStates.Won()):
-			rep += bet.Reputation
-		default:
-			continue // unknown state
-		}
+	return job, nil
+}
 
-		if err := gw.doReq(ctx, &req{
-			mtd: "PATCH",
-			res: "users",
-			url: userURL,
-			pay: &dto.Wrapper{Data: &map[string]interface{}{
-				"reputation": rep,
-			}},
-			err:   defaultResponseHandler,
-			parse: nil,
-		}); err != nil {
-			payload, _ := json.Marshal(bet)
-			log.Errorf("Failed to update user's karma, err: %s, payload: %s", err, string(payload))
-			continue
-		}
+// DryRunMatchers of free text topic, shows bets accepted by each matcher if the topic is answered
+func (gw *Gateway) DryRunMatchers(ctx context.Context, ans *command.Answer) ([]*dto.Matched, error) {
+	if ans.Topic == "" {
+		return nil, exception.New(http.StatusBadRequest, "Topic can't be empty")
 	}
+	if ans.Answer == "" {
+		return nil, exception.New(http.StatusBadRequest, "Answer can't be empty")
+	}
+
+	result := []*dto.Matched{}
+	return result, gw.doReq(ctx, &req{
+		mtd:   "POST",
+		res:   "matchers",
+		url:   gw.conf.GetDryRunURL(),
+		pay:   &dto.Wrapper{Data: ans},
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(&result),
+	})
+}
+
+// AnswerJob progress of settlement, final stat is available once done
+func (gw *Gateway) AnswerJob(ctx context.Context, id string) (*dto.Job, error) {
+	job := &dto.Job{}
+	return job, gw.doReq(ctx, &req{
+		mtd:   "GET",
+		res:   "settlements",
+		url:   gw.conf.GetSettlementURL(id),
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(job),
+	})
+}
 
-	return stat, nil
+// RetryAnswer of failed settlement, including its dead letters
+func (gw *Gateway) RetryAnswer(ctx context.Context, id string) (*dto.Job, error) {
+	job := &dto.Job{}
+	return job, gw.doReq(ctx, &req{
+		mtd:   "POST",
+		res:   "settlements",
+		url:   gw.conf.GetSettlementURL(id, "retry"),
+		err:   defaultResponseHandler,
+		parse: defaultResponseUnwrapper(job),
+	})
 }
 
 func (gw *Gateway) doReq(ctx context.Context, req *req) error {
 	var call func(string) (*resty.Response, error)
 	api := gw.rc.R().SetContext(ctx)
+	if email, ok := ctx.Value(global.Context.Email()).(string); ok {
+		api.SetHeader(global.Header.ActingAs(), email)
+	}
 	switch req.mtd {
 	case http.MethodPost:
 		call = api.Post
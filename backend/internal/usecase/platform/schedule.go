@@ -0,0 +1,78 @@
+package platform
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	topicDao "github.com/di-collective/ditebak/backend/internal/domain/topic/dao"
+	"github.com/di-collective/ditebak/backend/internal/usecase/platform/dto"
+)
+
+// PublishDue drafts whose publishing time has come
+func (gw *Gateway) PublishDue(ctx context.Context, now time.Time) (int, error) {
+	return gw.transitDue(ctx, url.Values{
+		"state":          []string{string(topicDao.TopicStates.Draft())},
+		"publish_before": []string{now.UTC().Format(time.RFC3339)},
+	}, topicDao.TopicStates.Published())
+}
+
+// CloseDue published topics whose closing time has come
+func (gw *Gateway) CloseDue(ctx context.Context, now time.Time) (int, error) {
+	return gw.transitDue(ctx, url.Values{
+		"state":          []string{string(topicDao.TopicStates.Published())},
+		"closing_before": []string{now.UTC().Format(time.RFC3339)},
+	}, topicDao.TopicStates.Closed())
+}
+
+// transitDue topics matching query into another state
+// Process:
+// 1. Find first page of due topics
+// 2. Transit each of them, transited topics no longer match the query
+// 3. Repeat until no more due topics, or nothing can be transited
+func (gw *Gateway) transitDue(ctx context.Context, query url.Values, to interface{}) (int, error) {
+	topicURL, _ := url.Parse(gw.conf.URL.Topic)
+	query.Set("page", "1")
+	query.Set("size", "100")
+	topicURL.RawQuery = query.Encode()
+
+	total := 0
+	for {
+		topics := []*dto.Topic{}
+		if err := gw.doReq(ctx, &req{
+			mtd:   "GET",
+			res:   "topics",
+			url:   topicURL.String(),
+			err:   defaultResponseHandler,
+			parse: defaultResponseUnwrapper(&topics),
+		}); err != nil {
+			return total, err
+		}
+
+		transited := 0
+		for _, topic := range topics {
+			if err := gw.doReq(ctx, &req{
+				mtd: "POST",
+				res: "topics",
+				url: gw.conf.GetTransitionURL(topic.ID),
+				pay: &dto.Wrapper{Data: &map[string]interface{}{
+					"state": to,
+				}},
+				err:   defaultResponseHandler,
+				parse: nil,
+			}); err != nil {
+				log.Errorf("Failed to move topic %s to %v, err: %v", topic.ID, to, err)
+				continue
+			}
+
+			transited++
+		}
+
+		total += transited
+		if len(topics) <= 0 || transited <= 0 {
+			return total, nil
+		}
+	}
+}
@@ -3,7 +3,13 @@ package global
 // Globalvar
 var (
 	Context = &context{
-		email: key("email"),
+		email:    key("email"),
+		identity: key("identity"),
+	}
+
+	Header = &header{
+		serviceKey: "X-Service-Key",
+		actingAs:   "X-Acting-As",
 	}
 )
 
@@ -14,9 +20,30 @@ func (k key) String() string {
 }
 
 type context struct {
-	email key
+	email    key
+	identity key
 }
 
 func (ctx *context) Email() key {
 	return ctx.email
 }
+
+func (ctx *context) Identity() key {
+	return ctx.identity
+}
+
+// header names shared between API gateways and resources
+type header struct {
+	serviceKey string
+	actingAs   string
+}
+
+// ServiceKey header carries shared secret of internal services
+func (h *header) ServiceKey() string {
+	return h.serviceKey
+}
+
+// ActingAs header carries email of user on whose behalf an internal service is calling
+func (h *header) ActingAs() string {
+	return h.actingAs
+}
@@ -11,8 +11,8 @@ import (
 
 // Serve HTTP gracefuly
 func Serve(listenAndServe func() error, teardown func(context.Context) error) error {
-	term := make(chan os.Signal) // OS termination signal
-	fail := make(chan error)     // Teardown failure signal
+	term := make(chan os.Signal, 1) // OS termination signal
+	fail := make(chan error)        // Teardown failure signal
 
 	go func() {
 		signal.Notify(term, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,110 @@
+// Package lease is a mongodb backed lock with expiry,
+// used to elect a single replica to run background jobs
+// lease is kept in a table of the shared database when repositories are kept in postgres,
+// it is always held when they are kept in memory or sqlite, as there is no other replica
+package lease
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo/driver"
+)
+
+// Lease on a named job
+type Lease struct {
+	coll   *mongo.Collection
+	db     *sql.DB
+	name   string
+	holder string
+	ttl    time.Duration
+}
+
+// New lease
+// @coll: mongo collection to store leases, its name is the table of leases in postgres
+// @name: name of job guarded by the lease
+// @ttl: lease expires when holder fails to renew it within ttl
+func New(coll *mongo.Collection, name string, ttl time.Duration) *Lease {
+	host, _ := os.Hostname()
+	l := &Lease{
+		coll:   coll,
+		name:   name,
+		holder: fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano()),
+		ttl:    ttl,
+	}
+
+	if driver.SQL() != nil && !driver.SingleReplica() {
+		l.db = driver.DB()
+		if _, err := l.db.Exec(createTable(coll.Name())); err != nil {
+			log.Errorf("Failed to create table %s: %v", coll.Name(), err)
+		}
+	}
+
+	return l
+}
+
+// Holder identity of this replica
+func (l *Lease) Holder() string {
+	return l.holder
+}
+
+// Acquire or renew the lease
+// returns false without error if lease is held by another replica
+func (l *Lease) Acquire(ctx context.Context) (bool, error) {
+	if driver.SingleReplica() {
+		return true, nil
+	}
+	if l.db != nil {
+		return l.acquireSQL(ctx)
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"_id": l.name,
+		"$or": []bson.M{
+			{"holder": l.holder},
+			{"expires_at": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"holder":     l.holder,
+		"expires_at": now.Add(l.ttl),
+	}}
+
+	// upsert conflicts on _id if lease is still held by another replica
+	_, err := l.coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mwe, ok := err.(mongo.WriteException); ok {
+		for _, e := range mwe.WriteErrors {
+			if e.Code == 11000 {
+				return false, nil
+			}
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Release the lease if held by this replica
+func (l *Lease) Release(ctx context.Context) error {
+	if driver.SingleReplica() {
+		return nil
+	}
+	if l.db != nil {
+		return l.releaseSQL(ctx)
+	}
+
+	_, err := l.coll.DeleteOne(ctx, bson.M{"_id": l.name, "holder": l.holder})
+	return err
+}
@@ -0,0 +1,61 @@
+package lease
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestSQL lease against POSTGRES_DSN, skipped without it
+func TestSQL(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+	os.Setenv("REPO_DRIVER", "postgres")
+	os.Setenv("SQL_DSN", dsn)
+
+	// collection is only used by its name, client never connects
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost"))
+	if err != nil {
+		t.Fatalf("mongo.NewClient() error = %v", err)
+	}
+
+	ctx := context.Background()
+	coll := client.Database("ditebak").Collection("leasetest")
+	first := New(coll, "job", time.Second)
+	second := New(coll, "job", time.Second)
+	defer first.db.Exec(`DROP TABLE IF EXISTS "leasetest"`)
+
+	tests := []struct {
+		name  string
+		lease *Lease
+		want  bool
+		wait  time.Duration
+	}{
+		{"first acquires", first, true, 0},
+		{"second waits", second, false, 0},
+		{"first renews", first, true, 0},
+		{"second takes expired", second, true, 1100 * time.Millisecond},
+		{"first waits", first, false, 0},
+	}
+
+	for _, tt := range tests {
+		time.Sleep(tt.wait)
+		got, err := tt.lease.Acquire(ctx)
+		if err != nil || got != tt.want {
+			t.Fatalf("%s: Acquire() = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	if err := second.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if got, err := first.Acquire(ctx); err != nil || !got {
+		t.Errorf("Acquire() after Release = %v, %v, want true", got, err)
+	}
+}
@@ -0,0 +1,36 @@
+package lease
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// createTable of leases, a row per job
+func createTable(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (name TEXT PRIMARY KEY, holder TEXT NOT NULL, expires_at TIMESTAMPTZ NOT NULL)`, table)
+}
+
+// acquireSQL inserts the lease or takes it over if held by this replica or expired
+// the upsert locks the row, so only one of concurrent replicas updates it
+func (l *Lease) acquireSQL(ctx context.Context) (bool, error) {
+	now := time.Now()
+	query := fmt.Sprintf(`INSERT INTO "%[1]s" (name, holder, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE "%[1]s".holder = EXCLUDED.holder OR "%[1]s".expires_at < $4`, l.coll.Name())
+
+	res, err := l.db.ExecContext(ctx, query, l.name, l.holder, now.Add(l.ttl), now)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// releaseSQL deletes the lease if held by this replica
+func (l *Lease) releaseSQL(ctx context.Context) error {
+	query := fmt.Sprintf(`DELETE FROM "%s" WHERE name = $1 AND holder = $2`, l.coll.Name())
+	_, err := l.db.ExecContext(ctx, query, l.name, l.holder)
+	return err
+}
@@ -0,0 +1,138 @@
+// Package driver selects repository implementation by REPO_DRIVER,
+// mongodb unless it is "memory" so the whole stack runs offline,
+// "sqlite" or "postgres" so small deployment runs without mongodb
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/memrepo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/sqlrepo"
+
+	// registers database/sql drivers
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var (
+	db     *sql.DB
+	dbOnce sync.Once
+)
+
+// Repo of either driver
+type Repo interface {
+	repo.Repository
+	repo.Transactor
+	repo.Mutator
+
+	// Name of the collection
+	Name() string
+
+	// InspectIndexes of the collection against declared indexes
+	InspectIndexes(ctx context.Context) (*mongorepo.IndexReport, error)
+
+	// SyncIndexes creates missing indexes
+	SyncIndexes(ctx context.Context) (*mongorepo.IndexReport, error)
+
+	// DropUnmanaged indexes, returns dropped index names
+	DropUnmanaged(ctx context.Context) ([]string, error)
+}
+
+// Memory is true when repositories are kept in memory
+// mongo collections are then only used by their name, client doesn't need to connect
+func Memory() bool {
+	return os.Getenv("REPO_DRIVER") == "memory"
+}
+
+// SQL dialect when repositories are kept in SQL database, nil otherwise
+// mongo collections are then only used by their name, client doesn't need to connect
+func SQL() sqlrepo.Dialect {
+	switch os.Getenv("REPO_DRIVER") {
+	case "sqlite":
+		return sqlrepo.SQLite
+	case "postgres":
+		return sqlrepo.Postgres
+	}
+
+	return nil
+}
+
+// SingleReplica is true when repositories can't be shared by replicas, i.e. memory or sqlite
+func SingleReplica() bool {
+	return Memory() || SQL() == sqlrepo.SQLite
+}
+
+// Database of mongodb at uri, connected only when repositories are kept in mongodb
+// otherwise its collections are only used by their name, so the stack runs without mongodb
+func Database(ctx context.Context, uri, name string) (*mongo.Database, error) {
+	if uri == "" && (Memory() || SQL() != nil) {
+		uri = "mongodb://localhost"
+	}
+
+	client, err := mongo.NewClient(options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, err
+	}
+	if Memory() || SQL() != nil {
+		return client.Database(name), nil
+	}
+
+	if err := client.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return client.Database(name), nil
+}
+
+// DB shared by SQL repositories, opened once from SQL_DSN (default ditebak.db for sqlite)
+// sqlite allows a single writer, concurrent writer waits for it in WAL mode
+// NOTE: sqlite requires cgo, docker/monolith.dockerfile builds with it
+func DB() *sql.DB {
+	dbOnce.Do(func() {
+		name, dsn := os.Getenv("REPO_DRIVER"), os.Getenv("SQL_DSN")
+		if name == "sqlite" {
+			name = "sqlite3"
+			if dsn == "" {
+				dsn = "ditebak.db?_busy_timeout=5000&_journal_mode=WAL"
+			}
+		}
+
+		var err error
+		if db, err = sql.Open(name, dsn); err != nil {
+			log.Fatalln("Failed to open SQL database:", err)
+		}
+	})
+
+	return db
+}
+
+// New Repo of the configured driver, memory repositories share memrepo.Default store
+// SQL repositories share DB
+// @coll: mongo collection
+// @sort: Please provide a default sort
+// @con: Please provide a function to a constructor/factory which return a pointer to a struct
+// @del: event delegates
+// @indexes: declared indexes of the collection
+func New(coll *mongo.Collection,
+	sort map[string]int,
+	con func() interface{},
+	del mongorepo.Event,
+	indexes ...mongorepo.Index) Repo {
+	if Memory() {
+		return memrepo.New(memrepo.Default, coll.Name(), sort, con, del).WithIndexes(indexes...)
+	}
+	if dialect := SQL(); dialect != nil {
+		return sqlrepo.New(DB(), dialect, coll.Name(), sort, con, del).WithIndexes(indexes...)
+	}
+
+	return mongorepo.New(coll, sort, con, del).WithIndexes(indexes...)
+}
@@ -0,0 +1,64 @@
+// Package bsonfilter holds helpers shared by repositories evaluating mongodb query filters themselves
+package bsonfilter
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Normalize filter into bson types, so its values compare and convert the same way as stored documents
+// e.g. time.Time into primitive.DateTime, int into int32 or int64, custom string type into string
+func Normalize(filter map[string]interface{}) (bson.M, error) {
+	if filter == nil {
+		return bson.M{}, nil
+	}
+
+	raw, err := bson.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	m := bson.M{}
+	return m, bson.Unmarshal(raw, &m)
+}
+
+// ToMap of an embedded document, nil if v is not a document
+func ToMap(v interface{}) bson.M {
+	switch x := v.(type) {
+	case primitive.M:
+		return x
+	case primitive.D:
+		return x.Map()
+	}
+
+	return nil
+}
+
+// IsOperators is true when every key of the document is an operator
+func IsOperators(m bson.M) bool {
+	for key := range m {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Truthy as mongodb reads argument of $exists, zero number and false are false, anything else is true
+func Truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case int32:
+		return x != 0
+	case int64:
+		return x != 0
+	case float64:
+		return x != 0
+	}
+
+	return true
+}
@@ -0,0 +1,239 @@
+package memrepo
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo/internal/bsonfilter"
+)
+
+// match document against mongodb query filter
+// supports equality, $and, $or, $nor, $eq, $ne, $in, $nin, $gt, $gte, $lt, $lte and $exists
+func match(doc bson.M, filter bson.M) (bool, error) {
+	for key, cond := range filter {
+		var ok bool
+		var err error
+		switch key {
+		case "$and", "$or", "$nor":
+			ok, err = matchLogical(doc, key, cond)
+		default:
+			value, exists := lookup(doc, key)
+			ok, err = matchField(value, exists, cond)
+		}
+
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// matchLogical of $and, $or, $nor over sub filters
+func matchLogical(doc bson.M, op string, cond interface{}) (bool, error) {
+	filters, ok := cond.(primitive.A)
+	if !ok {
+		return false, fmt.Errorf("%s must be an array", op)
+	}
+
+	for _, f := range filters {
+		ok, err := match(doc, bsonfilter.ToMap(f))
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case op == "$and" && !ok:
+			return false, nil
+		case op == "$or" && ok:
+			return true, nil
+		case op == "$nor" && ok:
+			return false, nil
+		}
+	}
+
+	return op != "$or", nil
+}
+
+// matchField value of document against condition, either operators or value to equal
+func matchField(value interface{}, exists bool, cond interface{}) (bool, error) {
+	ops := bsonfilter.ToMap(cond)
+	if len(ops) == 0 || !bsonfilter.IsOperators(ops) {
+		return equals(value, exists, cond), nil
+	}
+
+	for op, arg := range ops {
+		ok := false
+		switch op {
+		case "$eq":
+			ok = equals(value, exists, arg)
+		case "$ne":
+			ok = !equals(value, exists, arg)
+		case "$in", "$nin":
+			args, isArray := arg.(primitive.A)
+			if !isArray {
+				return false, fmt.Errorf("%s must be an array", op)
+			}
+			for _, a := range args {
+				if equals(value, exists, a) {
+					ok = true
+					break
+				}
+			}
+			ok = ok == (op == "$in")
+		case "$gt", "$gte", "$lt", "$lte":
+			ok = exists && rank(value) == rank(arg) && ordered(op, compare(value, arg))
+		case "$exists":
+			ok = exists == bsonfilter.Truthy(arg)
+		default:
+			return false, fmt.Errorf("unsupported operator %s", op)
+		}
+
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// equals as mongodb does, array matches if any of its elements equals, nil matches missing field
+func equals(value interface{}, exists bool, arg interface{}) bool {
+	if arg == nil {
+		return !exists || value == nil
+	}
+	if !exists {
+		return false
+	}
+	if arr, ok := value.(primitive.A); ok {
+		for _, v := range arr {
+			if compare(v, arg) == 0 {
+				return true
+			}
+		}
+	}
+
+	return compare(value, arg) == 0
+}
+
+func ordered(op string, c int) bool {
+	switch op {
+	case "$gt":
+		return c > 0
+	case "$gte":
+		return c >= 0
+	case "$lt":
+		return c < 0
+	}
+
+	return c <= 0
+}
+
+// lookup value of a dotted key, e.g. answer.choice
+func lookup(doc bson.M, key string) (interface{}, bool) {
+	var value interface{} = doc
+	for _, field := range strings.Split(key, ".") {
+		m := bsonfilter.ToMap(value)
+		if m == nil {
+			return nil, false
+		}
+
+		v, ok := m[field]
+		if !ok {
+			return nil, false
+		}
+		value = v
+	}
+
+	return value, true
+}
+
+// compare values in mongodb sort order, numbers are compared regardless of their type
+// values of different types are ordered by their type
+func compare(a, b interface{}) int {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		return ra - rb
+	}
+
+	switch x := a.(type) {
+	case string:
+		return strings.Compare(x, b.(string))
+	case primitive.ObjectID:
+		y := b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:])
+	case primitive.DateTime:
+		return sign(float64(x) - float64(b.(primitive.DateTime)))
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		} else if y {
+			return -1
+		}
+		return 1
+	}
+
+	if fa, ok := number(a); ok {
+		fb, _ := number(b)
+		return sign(fa - fb)
+	}
+	if reflect.DeepEqual(a, b) {
+		return 0
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// rank of type in mongodb sort order
+func rank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case int32, int64, float64:
+		return 1
+	case string:
+		return 2
+	case primitive.M, primitive.D:
+		return 3
+	case primitive.A:
+		return 4
+	case primitive.ObjectID:
+		return 5
+	case bool:
+		return 6
+	case primitive.DateTime:
+		return 7
+	}
+
+	return 8
+}
+
+func number(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+
+	return 0, false
+}
+
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+
+	return 0
+}
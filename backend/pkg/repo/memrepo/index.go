@@ -0,0 +1,126 @@
+package memrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo/internal/bsonfilter"
+	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+)
+
+// WithIndexes declared for the collection
+// unique and TTL indexes are enforced on every operation, others are only reported
+func (r *Repo) WithIndexes(indexes ...mongorepo.Index) *Repo {
+	r.indexes = append(r.indexes, indexes...)
+	return r
+}
+
+// InspectIndexes of the collection, declared indexes always exist in memory
+func (r *Repo) InspectIndexes(ctx context.Context) (*mongorepo.IndexReport, error) {
+	report := &mongorepo.IndexReport{
+		Collection: r.name,
+		Created:    []string{},
+		Missing:    []string{},
+		Verified:   []string{},
+		Drifted:    []*mongorepo.Drift{},
+		Unmanaged:  []string{},
+	}
+	for _, idx := range r.indexes {
+		report.Verified = append(report.Verified, idx.Name)
+	}
+
+	return report, nil
+}
+
+// SyncIndexes of the collection, nothing to create in memory
+func (r *Repo) SyncIndexes(ctx context.Context) (*mongorepo.IndexReport, error) {
+	return r.InspectIndexes(ctx)
+}
+
+// DropUnmanaged indexes of the collection, there is none in memory
+func (r *Repo) DropUnmanaged(ctx context.Context) ([]string, error) {
+	return []string{}, nil
+}
+
+// verifyUnique keys of doc against every unique index, except the document at i being replaced
+// store must be locked
+func (r *Repo) verifyUnique(doc *document, i int) error {
+	docs := r.store.collections[r.name]
+	for j, other := range docs {
+		if j != i && other.m["_id"] == doc.m["_id"] {
+			return duplicate(r.name, "_id_")
+		}
+	}
+
+	for _, idx := range r.indexes {
+		if !idx.Unique {
+			continue
+		}
+
+		partial, err := bsonfilter.Normalize(idx.Partial)
+		if err != nil {
+			return err
+		}
+		if ok, err := match(doc.m, partial); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		for j, other := range docs {
+			if j == i {
+				continue
+			}
+			if ok, _ := match(other.m, partial); ok && sameKeys(doc.m, other.m, idx.Keys) {
+				return duplicate(r.name, idx.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// expire documents older than TTL of the collection, store must be locked
+func (r *Repo) expire() {
+	for _, idx := range r.indexes {
+		if idx.TTL <= 0 || len(idx.Keys) == 0 {
+			continue
+		}
+
+		deadline := primitive.NewDateTimeFromTime(time.Now().Add(-idx.TTL))
+		kept := []*document{}
+		for _, doc := range r.store.collections[r.name] {
+			at, ok := doc.m[idx.Keys[0].Key].(primitive.DateTime)
+			if !ok || at > deadline {
+				kept = append(kept, doc)
+			}
+		}
+		r.store.collections[r.name] = kept
+	}
+}
+
+// sameKeys is true when both documents have the same values on every key, missing key equals nil
+func sameKeys(a, b bson.M, keys bson.D) bool {
+	for _, key := range keys {
+		va, _ := lookup(a, key.Key)
+		vb, _ := lookup(b, key.Key)
+		if compare(va, vb) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// duplicate key error as returned by mongodb
+func duplicate(collection, index string) error {
+	return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+		Code:    11000,
+		Message: fmt.Sprintf("E11000 duplicate key error collection: %s index: %s", collection, index),
+	}}}
+}
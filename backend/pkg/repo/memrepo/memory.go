@@ -0,0 +1,400 @@
+package memrepo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/internal/bsonfilter"
+	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+)
+
+// Repo abstraction
+// behaves as mongorepo.Repo, errors are the ones returned by mongodb driver, e.g. mongo.ErrNoDocuments
+type Repo struct {
+	store       *Store
+	name        string
+	sort        map[string]int
+	constructor func() interface{}
+	delegates   mongorepo.Event
+	indexes     []mongorepo.Index
+}
+
+// New Repo in memory
+// @store: holds documents, repositories sharing transaction must share the store
+// @name: name of the collection
+// @sort: Please provide a default sort
+// @con: Please provide a function to a constructor/factory which return a pointer to a struct
+// @del: event delegates, the same ones given to mongorepo
+func New(store *Store,
+	name string,
+	sort map[string]int,
+	con func() interface{},
+	del mongorepo.Event) *Repo {
+	return &Repo{
+		store:       store,
+		name:        name,
+		sort:        sort,
+		constructor: con,
+		delegates:   del,
+	}
+}
+
+// Name of the collection
+func (r *Repo) Name() string {
+	return r.name
+}
+
+// Get one
+func (r *Repo) Get(ctx context.Context, id string) (interface{}, error) {
+	log.Traceln(r.name, "GET", id)
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	_id, _ := primitive.ObjectIDFromHex(id)
+	i := r.indexOf(_id)
+	if i < 0 {
+		return r.constructor(), mongo.ErrNoDocuments
+	}
+
+	dbo := r.constructor()
+	err := bson.Unmarshal(r.docs()[i].raw, dbo)
+	repo.RecordVersion(ctx, r.docs()[i].raw)
+
+	return dbo, err
+}
+
+// Find multiple
+func (r *Repo) Find(ctx context.Context, opt repo.FindOptions) (int64, []interface{}, error) {
+	trace := fmt.Sprintf("%s %s", r.name, "FIND")
+
+	// 1. set query
+	fi := map[string]interface{}{}
+	for key, value := range opt.Params {
+		fi[key] = value
+	}
+	if !opt.IncludeRemoved {
+		fi["_deleted"] = map[string]bool{"$exists": false}
+	}
+	filter, err := bsonfilter.Normalize(fi)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	log.Traceln(trace, "Filter:", fi, "Page:", opt.Page, "Skip:", opt.Skip(), "Limit:", opt.Size)
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	matched := []*document{}
+	for _, doc := range r.docs() {
+		ok, err := match(doc.m, filter)
+		if err != nil {
+			return 0, nil, err
+		}
+		if ok {
+			matched = append(matched, doc)
+		}
+	}
+
+	// 2. set sort
+	if opt.Sort != nil {
+		sortDocuments(matched, bson.D{{Key: opt.Sort.Field, Value: sortDirection(opt.Sort)}})
+	} else if len(r.sort) > 0 {
+		keys := bson.D{}
+		for field, direction := range r.sort {
+			keys = append(keys, bson.E{Key: field, Value: direction})
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+		sortDocuments(matched, keys)
+	}
+
+	// 3. set paging
+	count := int64(len(matched))
+	skip := opt.Skip()
+	if skip > len(matched) {
+		skip = len(matched)
+	}
+	matched = matched[skip:]
+	if opt.Size > 0 && opt.Size < len(matched) {
+		matched = matched[:opt.Size]
+	}
+
+	result := []interface{}{}
+	for _, doc := range matched {
+		dbo := r.constructor()
+		if err := bson.Unmarshal(doc.raw, dbo); err != nil {
+			return 0, nil, err
+		}
+		repo.RecordVersion(ctx, doc.raw)
+
+		result = append(result, dbo)
+	}
+
+	log.Traceln(trace, "total:", count, "result:", result)
+	return count, result, nil
+}
+
+// Create a new object, _id is generated unless given
+func (r *Repo) Create(ctx context.Context, obj interface{}) error {
+	r.delegates.WillCreate(obj)
+
+	id, err := r.insert(ctx, obj)
+	if err != nil {
+		return err
+	}
+
+	r.delegates.DidCreate(obj, id)
+	return nil
+}
+
+// Update an existing object, fields of obj are set on top of the stored ones and its version is incremented
+// object is inserted if not found and delegate sets upsert, unless ctx expects a version of it
+func (r *Repo) Update(ctx context.Context, id string, obj interface{}) error {
+	uo := options.Update()
+	r.delegates.WillUpdate(obj, uo)
+
+	_id, _ := primitive.ObjectIDFromHex(id)
+	set, err := newDocument(obj)
+	if err != nil {
+		return err
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	var uid *primitive.ObjectID
+	if i := r.indexOf(_id); i >= 0 {
+		if err := r.verifyVersion(ctx, id, i); err != nil {
+			return err
+		}
+		if err := r.replace(i, merge(r.docs()[i].m, r.nextVersion(i, set.m))); err != nil {
+			return err
+		}
+	} else if err := r.missing(ctx, id); err != nil {
+		return err
+	} else if uo.Upsert != nil && *uo.Upsert {
+		set.m["_id"] = _id
+		set.m[repo.VersionField] = int64(1)
+		if err := r.append(set.m); err != nil {
+			return err
+		}
+		uid = &_id
+	}
+
+	r.delegates.DidUpdate(obj, uid)
+	return nil
+}
+
+// Delete an existing object virtually
+func (r *Repo) Delete(ctx context.Context, id string) error {
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	_id, _ := primitive.ObjectIDFromHex(id)
+	if i := r.indexOf(_id); i >= 0 {
+		if err := r.verifyVersion(ctx, id, i); err != nil {
+			return err
+		}
+		return r.replace(i, merge(r.docs()[i].m, r.nextVersion(i, bson.M{"_deleted": true})))
+	}
+
+	return r.missing(ctx, id)
+}
+
+// Remove an existing object physically
+func (r *Repo) Remove(ctx context.Context, id string) error {
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	_id, _ := primitive.ObjectIDFromHex(id)
+	if i := r.indexOf(_id); i >= 0 {
+		if err := r.verifyVersion(ctx, id, i); err != nil {
+			return err
+		}
+		docs := r.docs()
+		r.store.collections[r.name] = append(append([]*document{}, docs[:i]...), docs[i+1:]...)
+		return nil
+	}
+
+	return r.missing(ctx, id)
+}
+
+// WithTransaction runs fn inside a transaction of the store
+// joins the ongoing transaction if ctx already carries one
+func (r *Repo) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.store.WithTransaction(ctx, fn)
+}
+
+// Mutate fields of an existing object atomically and return the object after mutation, its version is incremented
+func (r *Repo) Mutate(ctx context.Context, id string, m repo.Mutation) (interface{}, error) {
+	log.Traceln(r.name, "MUTATE", id, m)
+
+	cond, err := bsonfilter.Normalize(m.If)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	_id, _ := primitive.ObjectIDFromHex(id)
+	i := r.indexOf(_id)
+	if i < 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	if ok, err := match(r.docs()[i].m, cond); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, repo.ErrUnmatched
+	}
+
+	mutated, err := mongorepo.Apply(r.docs()[i].m, m.Versioned())
+	if err != nil {
+		return nil, err
+	}
+	if err := r.replace(i, mutated); err != nil {
+		return nil, err
+	}
+
+	dbo := r.constructor()
+	return dbo, bson.Unmarshal(r.docs()[i].raw, dbo)
+}
+
+// docs of the collection, store must be locked
+func (r *Repo) docs() []*document {
+	r.expire()
+	return r.store.collections[r.name]
+}
+
+// indexOf document with the id, -1 if not found, store must be locked
+func (r *Repo) indexOf(id primitive.ObjectID) int {
+	for i, doc := range r.docs() {
+		if doc.m["_id"] == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// insert obj as a new document
+func (r *Repo) insert(ctx context.Context, obj interface{}) (primitive.ObjectID, error) {
+	doc, err := newDocument(obj)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	id, ok := doc.m["_id"].(primitive.ObjectID)
+	if !ok {
+		id = primitive.NewObjectID()
+		doc.m["_id"] = id
+	}
+
+	unlock := r.store.lock(ctx)
+	defer unlock()
+
+	return id, r.append(doc.m)
+}
+
+// append a new document, store must be locked
+func (r *Repo) append(m bson.M) error {
+	doc, err := newDocument(m)
+	if err != nil {
+		return err
+	}
+	if err := r.verifyUnique(doc, -1); err != nil {
+		return err
+	}
+
+	r.store.collections[r.name] = append(r.docs(), doc)
+	return nil
+}
+
+// replace document at i, store must be locked
+// the slice is copied so snapshot of ongoing transaction is kept intact
+func (r *Repo) replace(i int, m bson.M) error {
+	doc, err := newDocument(m)
+	if err != nil {
+		return err
+	}
+	if err := r.verifyUnique(doc, i); err != nil {
+		return err
+	}
+
+	docs := append([]*document{}, r.docs()...)
+	docs[i] = doc
+	r.store.collections[r.name] = docs
+	return nil
+}
+
+// verifyVersion of document at i against the one expected by ctx, store must be locked
+func (r *Repo) verifyVersion(ctx context.Context, id string, i int) error {
+	if version, ok := repo.ExpectedVersion(ctx, id); ok && version != repo.VersionOf(r.docs()[i].raw) {
+		return repo.ErrVersionMismatch
+	}
+
+	return nil
+}
+
+// missing object isn't an error, unless ctx expects a version of it
+func (r *Repo) missing(ctx context.Context, id string) error {
+	if _, ok := repo.ExpectedVersion(ctx, id); ok {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// nextVersion of document at i along with fields of set, as $inc does
+func (r *Repo) nextVersion(i int, set bson.M) bson.M {
+	return merge(set, bson.M{repo.VersionField: repo.VersionOf(r.docs()[i].raw) + 1})
+}
+
+// merge fields of set on top of doc as $set does
+func merge(doc, set bson.M) bson.M {
+	merged := bson.M{}
+	for key, value := range doc {
+		merged[key] = value
+	}
+	for key, value := range set {
+		merged[key] = value
+	}
+
+	return merged
+}
+
+// sortDocuments by keys, stable so documents of the same keys keep insertion order
+func sortDocuments(docs []*document, keys bson.D) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, key := range keys {
+			a, _ := lookup(docs[i].m, key.Key)
+			b, _ := lookup(docs[j].m, key.Key)
+			if c := compare(a, b); c != 0 {
+				if direction, _ := key.Value.(int); direction < 0 {
+					return c > 0
+				}
+				return c < 0
+			}
+		}
+
+		return false
+	})
+}
+
+func sortDirection(opt *repo.SortOption) int {
+	if opt.Descending {
+		return -1
+	}
+
+	return 1
+}
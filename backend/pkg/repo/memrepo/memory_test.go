@@ -0,0 +1,201 @@
+package memrepo_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/memrepo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, sort map[string]int, del *repotest.Delegate) repotest.Repo {
+		return memrepo.New(memrepo.NewStore(), "docs", sort, repotest.NewDoc, del)
+	})
+}
+
+// entry with a date, as ledger and session documents
+type entry struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty"`
+	User   string             `bson:"user"`
+	Reason string             `bson:"reason"`
+	At     time.Time          `bson:"at"`
+}
+
+func newEntry() interface{} {
+	return &entry{}
+}
+
+func TestUniqueIndex(t *testing.T) {
+	ctx := context.Background()
+	r := memrepo.New(memrepo.NewStore(), "entries", nil, newEntry, &repotest.Delegate{}).WithIndexes(
+		mongorepo.Index{Name: "user_grant", Keys: bson.D{{Key: "user", Value: 1}}, Unique: true, Partial: bson.M{"reason": "grant"}},
+	)
+
+	first := &entry{ID: primitive.NewObjectID(), User: "a", Reason: "grant"}
+	if err := r.Create(ctx, first); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		entry     *entry
+		duplicate bool
+	}{
+		{"same key in partial", &entry{User: "a", Reason: "grant"}, true},
+		{"same key outside partial", &entry{User: "a", Reason: "won"}, false},
+		{"another key in partial", &entry{User: "b", Reason: "grant"}, false},
+		{"same _id", &entry{ID: first.ID, User: "c", Reason: "won"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Create(ctx, tt.entry)
+			if got := isDuplicate(err); got != tt.duplicate {
+				t.Errorf("Create() error = %v, want duplicate %v", err, tt.duplicate)
+			}
+		})
+	}
+
+	// updating another entry into the partial filter conflicts as well
+	won := &entry{ID: primitive.NewObjectID(), User: "a", Reason: "won"}
+	if err := r.Create(ctx, won); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := r.Mutate(ctx, won.ID.Hex(), repo.Mutation{Set: map[string]interface{}{"reason": "grant"}}); !isDuplicate(err) {
+		t.Errorf("Mutate() into duplicate error = %v, want duplicate", err)
+	}
+	if err := r.Update(ctx, first.ID.Hex(), &entry{User: "a", Reason: "grant", At: time.Now()}); err != nil {
+		t.Errorf("Update() of itself error = %v", err)
+	}
+}
+
+func TestTTLIndex(t *testing.T) {
+	ctx := context.Background()
+	r := memrepo.New(memrepo.NewStore(), "sessions", nil, newEntry, &repotest.Delegate{}).WithIndexes(
+		mongorepo.Index{Name: "at_ttl", Keys: bson.D{{Key: "at", Value: 1}}, TTL: time.Hour},
+	)
+
+	now := time.Now()
+	expired := &entry{ID: primitive.NewObjectID(), User: "old", At: now.Add(-2 * time.Hour)}
+	fresh := &entry{ID: primitive.NewObjectID(), User: "new", At: now.Add(-30 * time.Minute)}
+	for _, e := range []*entry{expired, fresh} {
+		if err := r.Create(ctx, e); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	if _, err := r.Get(ctx, expired.ID.Hex()); err != mongo.ErrNoDocuments {
+		t.Errorf("Get() of expired error = %v, want %v", err, mongo.ErrNoDocuments)
+	}
+	if _, err := r.Get(ctx, fresh.ID.Hex()); err != nil {
+		t.Errorf("Get() of fresh error = %v", err)
+	}
+	if total, _, _ := r.Find(ctx, repo.FindOptions{}); total != 1 {
+		t.Errorf("Find() total = %d, want 1", total)
+	}
+}
+
+func TestTransactionAcrossCollections(t *testing.T) {
+	ctx := context.Background()
+	store := memrepo.NewStore()
+	users := memrepo.New(store, "users", nil, repotest.NewDoc, &repotest.Delegate{})
+	entries := memrepo.New(store, "entries", nil, newEntry, &repotest.Delegate{})
+
+	user := &repotest.Doc{ID: primitive.NewObjectID(), Name: "a"}
+	if err := users.Create(ctx, user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	errAbort := errors.New("abort")
+	err := users.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := users.Mutate(ctx, user.ID.Hex(), repo.Mutation{Inc: map[string]int64{"score": 10}}); err != nil {
+			return err
+		}
+		if err := entries.Create(ctx, &entry{User: "a", Reason: "grant"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("WithTransaction() error = %v, want %v", err, errAbort)
+	}
+
+	if obj, _ := users.Get(ctx, user.ID.Hex()); obj.(*repotest.Doc).Score != 0 {
+		t.Errorf("score after rollback = %d, want 0", obj.(*repotest.Doc).Score)
+	}
+	if total, _, _ := entries.Find(ctx, repo.FindOptions{}); total != 0 {
+		t.Errorf("entries after rollback = %d, want 0", total)
+	}
+
+	// the other repository joins the transaction through ctx
+	err = users.WithTransaction(ctx, func(ctx context.Context) error {
+		return entries.WithTransaction(ctx, func(ctx context.Context) error {
+			return entries.Create(ctx, &entry{User: "a", Reason: "grant"})
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction() error = %v", err)
+	}
+	if total, _, _ := entries.Find(ctx, repo.FindOptions{}); total != 1 {
+		t.Errorf("entries after commit = %d, want 1", total)
+	}
+}
+
+func TestConcurrentMutate(t *testing.T) {
+	ctx := context.Background()
+	r := memrepo.New(memrepo.NewStore(), "users", nil, repotest.NewDoc, &repotest.Delegate{})
+	user := &repotest.Doc{ID: primitive.NewObjectID()}
+	if err := r.Create(ctx, user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.WithTransaction(ctx, func(ctx context.Context) error {
+				_, err := r.Mutate(ctx, user.ID.Hex(), repo.Mutation{Inc: map[string]int64{"score": 2}})
+				return err
+			})
+		}()
+	}
+	wg.Wait()
+
+	obj, err := r.Get(ctx, user.ID.Hex())
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := obj.(*repotest.Doc); got.Score != 100 || got.Version != 50 {
+		t.Errorf("after 50 increments score = %d version = %d, want 100 and 50", got.Score, got.Version)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	ctx := context.Background()
+	store := memrepo.NewStore()
+	r := memrepo.New(store, "docs", nil, repotest.NewDoc, &repotest.Delegate{})
+	if err := r.Create(ctx, &repotest.Doc{Name: "a"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	store.Drop()
+	if total, _, _ := r.Find(ctx, repo.FindOptions{}); total != 0 {
+		t.Errorf("Find() after Drop total = %d, want 0", total)
+	}
+}
+
+func isDuplicate(err error) bool {
+	we, ok := err.(mongo.WriteException)
+	return ok && len(we.WriteErrors) > 0 && we.WriteErrors[0].Code == 11000
+}
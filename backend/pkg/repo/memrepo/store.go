@@ -0,0 +1,94 @@
+package memrepo
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Default store shared by repositories of the process, so transaction spans every collection
+var Default = NewStore()
+
+// Store of in-memory collections
+// every operation is serialized, transaction holds the store until it commits or rollbacks
+type Store struct {
+	mu          sync.Mutex
+	collections map[string][]*document
+}
+
+// document stored as raw bson to be decoded into any struct, and as map to be matched against filters
+type document struct {
+	raw bson.Raw
+	m   bson.M
+}
+
+// txKey marks context running inside a transaction of a store
+type txKey struct{}
+
+// NewStore without any document
+func NewStore() *Store {
+	return &Store{collections: map[string][]*document{}}
+}
+
+// WithTransaction runs fn while holding the store, documents are restored when fn returns an error
+// joins the ongoing transaction if ctx already carries one
+func (s *Store) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.joined(ctx) {
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make(map[string][]*document, len(s.collections))
+	for name, docs := range s.collections {
+		snapshot[name] = append([]*document{}, docs...)
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, s)); err != nil {
+		s.collections = snapshot
+		return err
+	}
+
+	return nil
+}
+
+// Drop every document, e.g. between tests
+func (s *Store) Drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.collections = map[string][]*document{}
+}
+
+// joined is true when ctx runs inside a transaction of this store
+func (s *Store) joined(ctx context.Context) bool {
+	tx, _ := ctx.Value(txKey{}).(*Store)
+	return tx == s
+}
+
+// lock the store unless ctx already holds it through a transaction
+func (s *Store) lock(ctx context.Context) func() {
+	if s.joined(ctx) {
+		return func() {}
+	}
+
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// newDocument from any object marshallable into bson
+func newDocument(obj interface{}) (*document, error) {
+	raw, err := bson.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	m := bson.M{}
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+
+	return &document{raw: raw, m: m}, nil
+}
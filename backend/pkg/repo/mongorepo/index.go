@@ -0,0 +1,240 @@
+package mongorepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Index declaration of a collection, managed by its name
+type Index struct {
+	Name    string        // identifies the index, must be unique in the collection
+	Keys    bson.D        // field to 1, -1 or "text", in order of compound index
+	Unique  bool          // rejects duplicate keys
+	TTL     time.Duration // removes document once its date key is older than TTL, 0 disables
+	Partial bson.M        // only indexes documents matching the filter
+}
+
+// Drift of an index, declared but exists with different spec
+type Drift struct {
+	Name     string `json:"name"`
+	Declared string `json:"declared"`
+	Actual   string `json:"actual"`
+}
+
+// IndexReport of a collection
+type IndexReport struct {
+	Collection string   `json:"collection"`
+	Created    []string `json:"created"`   // declared and created
+	Missing    []string `json:"missing"`   // declared but not created
+	Verified   []string `json:"verified"`  // declared and exists with the same spec
+	Drifted    []*Drift `json:"drifted"`   // declared but exists with different spec, left as is
+	Unmanaged  []string `json:"unmanaged"` // exists but not declared, except _id
+}
+
+// WithIndexes declared for the collection, created by SyncIndexes
+func (r *Repo) WithIndexes(indexes ...Index) *Repo {
+	r.indexes = append(r.indexes, indexes...)
+	return r
+}
+
+// InspectIndexes of the collection against declared indexes, nothing is changed
+func (r *Repo) InspectIndexes(ctx context.Context) (*IndexReport, error) {
+	actual, err := r.listIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &IndexReport{
+		Collection: r.collection.Name(),
+		Created:    []string{},
+		Missing:    []string{},
+		Verified:   []string{},
+		Drifted:    []*Drift{},
+		Unmanaged:  []string{},
+	}
+
+	declared := map[string]bool{}
+	for _, idx := range r.indexes {
+		declared[idx.Name] = true
+		spec, ok := actual[idx.Name]
+		switch {
+		case !ok:
+			report.Missing = append(report.Missing, idx.Name)
+		case spec.describe() != idx.describe():
+			report.Drifted = append(report.Drifted, &Drift{
+				Name:     idx.Name,
+				Declared: idx.describe(),
+				Actual:   spec.describe(),
+			})
+		default:
+			report.Verified = append(report.Verified, idx.Name)
+		}
+	}
+
+	for name := range actual {
+		if name != "_id_" && !declared[name] {
+			report.Unmanaged = append(report.Unmanaged, name)
+		}
+	}
+
+	return report, nil
+}
+
+// SyncIndexes creates missing indexes, drifted and unmanaged ones are only reported
+// drifted index must be dropped to be recreated, e.g. through DropIndexes
+func (r *Repo) SyncIndexes(ctx context.Context) (*IndexReport, error) {
+	report, err := r.InspectIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	models := []mongo.IndexModel{}
+	for _, idx := range r.indexes {
+		for _, name := range report.Missing {
+			if idx.Name == name {
+				models = append(models, idx.model())
+			}
+		}
+	}
+
+	if len(models) > 0 {
+		if _, err = r.collection.Indexes().CreateMany(ctx, models); err != nil {
+			return report, err
+		}
+		report.Created, report.Missing = report.Missing, []string{}
+	}
+
+	for _, drift := range report.Drifted {
+		log.Warnf("Index %s of %s is drifted, declared: %s, actual: %s", drift.Name, report.Collection, drift.Declared, drift.Actual)
+	}
+	if len(report.Unmanaged) > 0 {
+		log.Warnf("Indexes of %s are unmanaged: %v", report.Collection, report.Unmanaged)
+	}
+	log.Infof("Indexes of %s are synced, created: %v, verified: %v", report.Collection, report.Created, report.Verified)
+
+	return report, nil
+}
+
+// DropIndexes by name, declared index is recreated by next SyncIndexes
+func (r *Repo) DropIndexes(ctx context.Context, names ...string) error {
+	for _, name := range names {
+		if _, err := r.collection.Indexes().DropOne(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DropUnmanaged indexes of the collection, returns dropped index names
+func (r *Repo) DropUnmanaged(ctx context.Context) ([]string, error) {
+	report, err := r.InspectIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Unmanaged, r.DropIndexes(ctx, report.Unmanaged...)
+}
+
+// indexSpec as listed by mongodb
+type indexSpec struct {
+	Name    string      `bson:"name"`
+	Key     bson.D      `bson:"key"`
+	Unique  bool        `bson:"unique"`
+	Expire  interface{} `bson:"expireAfterSeconds"`
+	Partial bson.M      `bson:"partialFilterExpression"`
+	Weights bson.D      `bson:"weights"` // fields of text index
+}
+
+// listIndexes of the collection by name
+func (r *Repo) listIndexes(ctx context.Context) (map[string]*indexSpec, error) {
+	cursor, err := r.collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	specs := map[string]*indexSpec{}
+	for cursor.Next(ctx) {
+		spec := &indexSpec{}
+		if err := cursor.Decode(spec); err != nil {
+			return nil, err
+		}
+		specs[spec.Name] = spec
+	}
+
+	return specs, cursor.Err()
+}
+
+// describe spec of listed index, text keys are listed as weights
+func (spec *indexSpec) describe() string {
+	keys := bson.D{}
+	for _, key := range spec.Key {
+		switch key.Key {
+		case "_fts":
+			for _, w := range spec.Weights {
+				keys = append(keys, bson.E{Key: w.Key, Value: "text"})
+			}
+		case "_ftsx":
+		default:
+			keys = append(keys, key)
+		}
+	}
+
+	ttl := ""
+	if spec.Expire != nil {
+		ttl = fmt.Sprint(spec.Expire)
+	}
+	return describe(keys, spec.Unique, ttl, spec.Partial)
+}
+
+// describe spec of declared index
+func (idx *Index) describe() string {
+	ttl := ""
+	if idx.TTL > 0 {
+		ttl = fmt.Sprint(int32(idx.TTL.Seconds()))
+	}
+	return describe(idx.Keys, idx.Unique, ttl, idx.Partial)
+}
+
+// model of declared index to be created
+func (idx *Index) model() mongo.IndexModel {
+	opt := options.Index().SetName(idx.Name)
+	if idx.Unique {
+		opt.SetUnique(true)
+	}
+	if idx.TTL > 0 {
+		opt.SetExpireAfterSeconds(int32(idx.TTL.Seconds()))
+	}
+	if idx.Partial != nil {
+		opt.SetPartialFilterExpression(idx.Partial)
+	}
+
+	return mongo.IndexModel{Keys: idx.Keys, Options: opt}
+}
+
+// describe index spec comparably, numbers are compared regardless of their type
+func describe(keys bson.D, unique bool, ttl string, partial bson.M) string {
+	desc := "keys:"
+	for _, key := range keys {
+		desc += fmt.Sprintf(" %s=%v", key.Key, key.Value)
+	}
+	if unique {
+		desc += ", unique"
+	}
+	if ttl != "" {
+		desc += ", ttl: " + ttl + "s"
+	}
+	if len(partial) > 0 {
+		desc += fmt.Sprintf(", partial: %v", partial)
+	}
+
+	return desc
+}
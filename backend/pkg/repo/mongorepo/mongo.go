@@ -15,7 +15,7 @@ import (
 )
 
 var (
-	virtualDelete = bson.M{"$set": bson.M{"_deleted": true}}
+	virtualDelete = bson.M{"$set": bson.M{"_deleted": true}, "$inc": bson.M{repo.VersionField: 1}}
 )
 
 // Repo abstraction
@@ -24,6 +24,7 @@ type Repo struct {
 	sort        map[string]int
 	constructor func() interface{}
 	delegates   Event
+	indexes     []Index
 }
 
 // New Repo using mongodb
@@ -43,16 +44,24 @@ func New(coll *mongo.Collection,
 	}
 }
 
+// Name of the collection
+func (r *Repo) Name() string {
+	return r.collection.Name()
+}
+
 // Get one
 func (r *Repo) Get(ctx context.Context, id string) (interface{}, error) {
 	log.Traceln(r.collection.Name(), "GET", id)
 
 	_id, _ := primitive.ObjectIDFromHex(id)
-	res := r.collection.FindOne(ctx, bson.M{"_id": _id})
 	dbo := r.constructor()
-	err := res.Decode(dbo)
+	raw, err := r.collection.FindOne(ctx, bson.M{"_id": _id}).DecodeBytes()
+	if err != nil {
+		return dbo, err
+	}
 
-	return dbo, err
+	repo.RecordVersion(ctx, raw)
+	return dbo, bson.Unmarshal(raw, dbo)
 }
 
 // Find multiple
@@ -92,6 +101,7 @@ func (r *Repo) Find(ctx context.Context, opt repo.FindOptions) (int64, []interfa
 		if err = cur.Decode(dbo); err != nil {
 			return 0, nil, err
 		}
+		repo.RecordVersion(ctx, cur.Current)
 
 		result = append(result, dbo)
 	}
@@ -115,18 +125,32 @@ func (r *Repo) Create(ctx context.Context, obj interface{}) error {
 	return nil
 }
 
-// Update an existing object
+// Update an existing object, its version is incremented
+// object of another version than expected by ctx is not updated nor upserted
 func (r *Repo) Update(ctx context.Context, id string, obj interface{}) error {
 	uo := options.Update()
 	r.delegates.WillUpdate(obj, uo)
 
 	_id, _ := primitive.ObjectIDFromHex(id)
-	setter := bson.M{"$set": obj}
-	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": _id}, setter, uo)
+	filter, expected := r.versionFilter(ctx, _id, id)
+	if expected {
+		uo.SetUpsert(false)
+	}
+
+	set, err := withoutVersion(obj)
 	if err != nil {
 		return err
 	}
 
+	setter := bson.M{"$set": set, "$inc": bson.M{repo.VersionField: 1}}
+	res, err := r.collection.UpdateOne(ctx, filter, setter, uo)
+	if err != nil {
+		return err
+	}
+	if expected && res.MatchedCount == 0 {
+		return r.mismatch(ctx, _id)
+	}
+
 	var uid *primitive.ObjectID
 	if res.UpsertedID != nil {
 		pid, _ := res.UpsertedID.(primitive.ObjectID)
@@ -137,13 +161,17 @@ func (r *Repo) Update(ctx context.Context, id string, obj interface{}) error {
 	return nil
 }
 
-// Delete an existing object virtually
+// Delete an existing object virtually, its version is incremented
 func (r *Repo) Delete(ctx context.Context, id string) error {
 	_id, _ := primitive.ObjectIDFromHex(id)
-	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": _id}, virtualDelete)
+	filter, expected := r.versionFilter(ctx, _id, id)
+	res, err := r.collection.UpdateOne(ctx, filter, virtualDelete)
 	if err != nil {
 		return err
 	}
+	if expected && res.MatchedCount == 0 {
+		return r.mismatch(ctx, _id)
+	}
 
 	return nil
 }
@@ -151,14 +179,132 @@ func (r *Repo) Delete(ctx context.Context, id string) error {
 // Remove an existing object physically
 func (r *Repo) Remove(ctx context.Context, id string) error {
 	_id, _ := primitive.ObjectIDFromHex(id)
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": _id})
+	filter, expected := r.versionFilter(ctx, _id, id)
+	res, err := r.collection.DeleteOne(ctx, filter)
 	if err != nil {
 		return err
 	}
+	if expected && res.DeletedCount == 0 {
+		return r.mismatch(ctx, _id)
+	}
 
 	return nil
 }
 
+// WithTransaction runs fn inside a mongodb transaction
+// joins the ongoing transaction if ctx already carries one
+// NOTE: mongodb must run as replica set to support transaction
+func (r *Repo) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if sc, ok := ctx.(mongo.SessionContext); ok {
+		return fn(sc)
+	}
+
+	session, err := r.collection.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// Mutate fields of an existing object atomically and return the object after mutation, its version is incremented
+func (r *Repo) Mutate(ctx context.Context, id string, m repo.Mutation) (interface{}, error) {
+	log.Traceln(r.collection.Name(), "MUTATE", id, m)
+
+	_id, _ := primitive.ObjectIDFromHex(id)
+	filter := bson.M{}
+	for k, v := range m.If {
+		filter[k] = v
+	}
+	filter["_id"] = _id
+
+	update := bson.M{"$inc": m.Versioned().Inc}
+	if len(m.Set) > 0 {
+		update["$set"] = m.Set
+	}
+	if len(m.Push) > 0 {
+		update["$push"] = m.Push
+	}
+	if len(m.Pull) > 0 {
+		update["$pull"] = m.Pull
+	}
+
+	dbo := r.constructor()
+	opt := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opt).Decode(dbo)
+	if err == mongo.ErrNoDocuments && len(m.If) > 0 {
+		// tell whether it doesn't exist or doesn't match
+		n, cerr := r.collection.CountDocuments(ctx, bson.M{"_id": _id})
+		if cerr != nil {
+			return nil, cerr
+		}
+		if n > 0 {
+			return nil, repo.ErrUnmatched
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dbo, nil
+}
+
+// withoutVersion document of obj to $set, version read along the object is incremented instead
+// as mongodb rejects $set and $inc of the same field
+func withoutVersion(obj interface{}) (bson.D, error) {
+	raw, err := bson.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.D{}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	set := bson.D{}
+	for _, e := range doc {
+		if e.Key != repo.VersionField {
+			set = append(set, e)
+		}
+	}
+	return set, nil
+}
+
+// versionFilter of the object, matches only the version expected by ctx if any
+// document without version is of version 0
+func (r *Repo) versionFilter(ctx context.Context, _id primitive.ObjectID, id string) (bson.M, bool) {
+	filter := bson.M{"_id": _id}
+	version, ok := repo.ExpectedVersion(ctx, id)
+	if !ok {
+		return filter, false
+	}
+
+	if version == 0 {
+		filter[repo.VersionField] = bson.M{"$exists": false}
+	} else {
+		filter[repo.VersionField] = version
+	}
+	return filter, true
+}
+
+// mismatch of version when nothing is matched, mongo.ErrNoDocuments if the object doesn't exist
+func (r *Repo) mismatch(ctx context.Context, _id primitive.ObjectID) error {
+	n, err := r.collection.CountDocuments(ctx, bson.M{"_id": _id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return repo.ErrVersionMismatch
+}
+
 func sortDirection(opt *repo.SortOption) int {
 	if opt.Descending {
 		return -1
@@ -0,0 +1,72 @@
+package mongorepo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/repotest"
+)
+
+// TestConformance runs against MONGO_URI, skipped without it
+// transactions need a replica set, e.g. mongodb://localhost:27017/?replicaSet=rs0
+func TestConformance(t *testing.T) {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI is not set")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("mongo.Connect() error = %v", err)
+	}
+	defer client.Disconnect(ctx)
+
+	db := client.Database(fmt.Sprintf("repotest_%d", os.Getpid()))
+	defer db.Drop(ctx)
+
+	n := 0
+	repotest.Run(t, func(t *testing.T, sort map[string]int, del *repotest.Delegate) repotest.Repo {
+		n++
+		coll := db.Collection(fmt.Sprintf("docs_%d", n))
+		// created up front, transactions of older mongodb can't create it
+		if err := db.RunCommand(ctx, bson.D{{Key: "create", Value: coll.Name()}}).Err(); err != nil {
+			t.Fatalf("create collection error = %v", err)
+		}
+
+		return New(coll, sort, repotest.NewDoc, del)
+	})
+}
+
+func TestWithoutVersion(t *testing.T) {
+	id := primitive.NewObjectID()
+	tests := []struct {
+		name string
+		obj  interface{}
+		want bson.D
+	}{
+		{"version read along", &repotest.Doc{ID: id, Name: "a", Version: 3}, bson.D{{Key: "_id", Value: id}, {Key: "name", Value: "a"}}},
+		{"without version", &repotest.Doc{Score: 2}, bson.D{{Key: "score", Value: int64(2)}}},
+		{"map", bson.M{repo.VersionField: int64(1)}, bson.D{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withoutVersion(tt.obj)
+			if err != nil {
+				t.Fatalf("withoutVersion() error = %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("withoutVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package mongorepo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+)
+
+// Apply mutation on a decoded document as mongodb applies $inc, $set, $push and $pull,
+// for repositories keeping documents elsewhere. m.If is not checked, doc is left untouched
+func Apply(doc bson.M, m repo.Mutation) (bson.M, error) {
+	// values go through bson so they compare and encode as stored ones
+	set, err := bsonOf(m.Set)
+	if err != nil {
+		return nil, err
+	}
+	push, err := bsonOf(m.Push)
+	if err != nil {
+		return nil, err
+	}
+	pull, err := bsonOf(m.Pull)
+	if err != nil {
+		return nil, err
+	}
+
+	out := copyOf(doc)
+	for field, delta := range m.Inc {
+		var sum interface{}
+		switch v := get(out, field).(type) {
+		case nil:
+			sum = delta
+		case int32:
+			sum = int64(v) + delta
+		case int64:
+			sum = v + delta
+		case float64:
+			sum = v + float64(delta)
+		default:
+			return nil, fmt.Errorf("cannot increment non-numeric field %s of type %T", field, v)
+		}
+		if err := put(out, field, sum); err != nil {
+			return nil, err
+		}
+	}
+
+	for field, value := range set {
+		if err := put(out, field, value); err != nil {
+			return nil, err
+		}
+	}
+
+	for field, value := range push {
+		arr, err := array(out, field)
+		if err != nil {
+			return nil, err
+		}
+		if err := put(out, field, append(arr, value)); err != nil {
+			return nil, err
+		}
+	}
+
+	for field, value := range pull {
+		arr, err := array(out, field)
+		if err != nil {
+			return nil, err
+		}
+
+		kept := primitive.A{}
+		for _, e := range arr {
+			if !same(e, value) {
+				kept = append(kept, e)
+			}
+		}
+		if err := put(out, field, kept); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// bsonOf fields, values marshalled into their bson types
+func bsonOf(fields map[string]interface{}) (bson.M, error) {
+	m := bson.M{}
+	if len(fields) == 0 {
+		return m, nil
+	}
+
+	raw, err := bson.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, bson.Unmarshal(raw, &m)
+}
+
+// copyOf document deep enough that put doesn't touch the original
+func copyOf(doc bson.M) bson.M {
+	out := bson.M{}
+	for key, value := range doc {
+		if sub := embedded(value); sub != nil {
+			value = copyOf(sub)
+		}
+		out[key] = value
+	}
+
+	return out
+}
+
+// get value of a dotted field, nil if missing
+func get(doc bson.M, field string) interface{} {
+	var value interface{} = doc
+	for _, key := range strings.Split(field, ".") {
+		m := embedded(value)
+		if m == nil {
+			return nil
+		}
+		value = m[key]
+	}
+
+	return value
+}
+
+// put value at a dotted field, missing embedded documents are created
+func put(doc bson.M, field string, value interface{}) error {
+	keys := strings.Split(field, ".")
+	m := doc
+	for _, key := range keys[:len(keys)-1] {
+		switch v := m[key].(type) {
+		case nil:
+			sub := bson.M{}
+			m[key] = sub
+			m = sub
+		case primitive.M:
+			m = v
+		case primitive.D:
+			sub := copyOf(v.Map())
+			m[key] = sub
+			m = sub
+		default:
+			return fmt.Errorf("cannot set %s inside field %s of type %T", field, key, v)
+		}
+	}
+	m[keys[len(keys)-1]] = value
+
+	return nil
+}
+
+// array at a dotted field, copied so it can be appended, empty if missing
+func array(doc bson.M, field string) (primitive.A, error) {
+	switch v := get(doc, field).(type) {
+	case nil:
+		return primitive.A{}, nil
+	case primitive.A:
+		return append(primitive.A{}, v...), nil
+	default:
+		return nil, fmt.Errorf("cannot push or pull non-array field %s of type %T", field, v)
+	}
+}
+
+// embedded document of v, nil if v is not a document
+func embedded(v interface{}) bson.M {
+	switch x := v.(type) {
+	case primitive.M:
+		return x
+	case primitive.D:
+		return x.Map()
+	}
+
+	return nil
+}
+
+// same values as $pull matches them, numbers are equal regardless of their type
+func same(a, b interface{}) bool {
+	if fa, ok := float(a); ok {
+		fb, ok := float(b)
+		return ok && fa == fb
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func float(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+
+	return 0, false
+}
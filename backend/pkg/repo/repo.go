@@ -2,8 +2,12 @@ package repo
 
 import (
 	"context"
+	"errors"
 )
 
+// ErrUnmatched is returned by Mutator when the object exists but doesn't match Mutation.If
+var ErrUnmatched = errors.New("object doesn't match the condition of mutation")
+
 // Repository level abstraction
 type Repository interface {
 	Reader
@@ -33,3 +37,27 @@ type Writer interface {
 	// Remove an existing object physically
 	Remove(ctx context.Context, id string) error
 }
+
+// Transactor runs multiple operations as a single unit of work
+type Transactor interface {
+	// WithTransaction commits when fn returns nil, otherwise rollbacks
+	// repositories must be called with ctx given to fn to take part in the transaction
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Mutation of fields of an object, applied atomically in order of Inc, Set, Push, Pull
+// field may be a dotted path into embedded document
+type Mutation struct {
+	If   map[string]interface{} // filter the object must match, e.g. the expected value of a field to set it only if unchanged
+	Inc  map[string]int64       // increment numeric fields by delta, negative delta decrements
+	Set  map[string]interface{} // set fields to value
+	Push map[string]interface{} // append value to array fields, missing field is created
+	Pull map[string]interface{} // remove every element equal to value from array fields
+}
+
+// Mutator abstraction to atomic field operations
+type Mutator interface {
+	// Mutate fields of an existing object atomically and return the object after mutation
+	// mongo.ErrNoDocuments when it doesn't exist, ErrUnmatched when it doesn't match m.If
+	Mutate(ctx context.Context, id string, m Mutation) (interface{}, error)
+}
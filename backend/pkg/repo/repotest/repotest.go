@@ -0,0 +1,501 @@
+// Package repotest is the conformance suite every repository implementation must pass,
+// so memory and SQL repositories keep behaving as mongorepo does
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+)
+
+// Repo under test
+type Repo interface {
+	repo.Repository
+	repo.Transactor
+	repo.Mutator
+}
+
+// Open a new empty Repo of Doc
+// @sort: default sort of the repository
+// @del: event delegates of the repository
+type Open func(t *testing.T, sort map[string]int, del *Delegate) Repo
+
+// Doc stored by the suite
+type Doc struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty"`
+	Name    string             `bson:"name,omitempty"`
+	Score   int64              `bson:"score,omitempty"`
+	Tags    []string           `bson:"tags,omitempty"`
+	Version int64              `bson:"_version,omitempty"`
+	Deleted bool               `bson:"_deleted,omitempty"`
+}
+
+// NewDoc constructor of the repository
+func NewDoc() interface{} {
+	return &Doc{}
+}
+
+// Delegate records events of the repository, upserts when Upsert is set
+type Delegate struct {
+	Upsert   bool
+	Created  []primitive.ObjectID
+	Updated  int
+	Upserted []primitive.ObjectID
+}
+
+// WillCreate ...
+func (d *Delegate) WillCreate(interface{}) {}
+
+// DidCreate ...
+func (d *Delegate) DidCreate(_ interface{}, id primitive.ObjectID) {
+	d.Created = append(d.Created, id)
+}
+
+// WillUpdate ...
+func (d *Delegate) WillUpdate(_ interface{}, uo *options.UpdateOptions) {
+	if d.Upsert {
+		uo.SetUpsert(true)
+	}
+}
+
+// DidUpdate ...
+func (d *Delegate) DidUpdate(_ interface{}, upsert *primitive.ObjectID) {
+	d.Updated++
+	if upsert != nil {
+		d.Upserted = append(d.Upserted, *upsert)
+	}
+}
+
+// Run the suite against repositories opened by open
+func Run(t *testing.T, open Open) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, open Open)
+	}{
+		{"Paging", testPaging},
+		{"Sort", testSort},
+		{"Filter", testFilter},
+		{"SoftDelete", testSoftDelete},
+		{"Delegates", testDelegates},
+		{"Mutate", testMutate},
+		{"Versions", testVersions},
+		{"Transaction", testTransaction},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) { tt.fn(t, open) })
+	}
+}
+
+func testPaging(t *testing.T, open Open) {
+	r := open(t, map[string]int{"score": 1}, &Delegate{})
+	seed(t, r, "a", "b", "c", "d", "e")
+
+	tests := []struct {
+		page, size int
+		want       []int64
+	}{
+		{page: 0, size: 2, want: []int64{1, 2}},
+		{page: 1, size: 2, want: []int64{1, 2}},
+		{page: 2, size: 2, want: []int64{3, 4}},
+		{page: 3, size: 2, want: []int64{5}},
+		{page: 4, size: 2, want: []int64{}},
+		{page: 1, size: 0, want: []int64{1, 2, 3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		total, rows := find(t, r, repo.FindOptions{Page: tt.page, Size: tt.size})
+		if total != 5 {
+			t.Errorf("page %d size %d: total = %d, want 5", tt.page, tt.size, total)
+		}
+		assertScores(t, rows, tt.want)
+	}
+}
+
+func testSort(t *testing.T, open Open) {
+	r := open(t, map[string]int{"score": -1}, &Delegate{})
+	seed(t, r, "c", "a", "b")
+
+	tests := []struct {
+		name string
+		sort *repo.SortOption
+		want []int64
+	}{
+		{"default", nil, []int64{3, 2, 1}},
+		{"ascending", &repo.SortOption{Field: "name"}, []int64{2, 3, 1}},
+		{"descending", &repo.SortOption{Field: "name", Descending: true}, []int64{1, 3, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rows := find(t, r, repo.FindOptions{Sort: tt.sort})
+			assertScores(t, rows, tt.want)
+		})
+	}
+}
+
+func testFilter(t *testing.T, open Open) {
+	r := open(t, map[string]int{"score": 1}, &Delegate{})
+	seed(t, r, "a", "b", "c", "d", "e")
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   []int64
+	}{
+		{"equal", map[string]interface{}{"name": "c"}, []int64{3}},
+		{"in", map[string]interface{}{"name": map[string]interface{}{"$in": []string{"a", "d", "z"}}}, []int64{1, 4}},
+		{"nin", map[string]interface{}{"name": map[string]interface{}{"$nin": []string{"a", "d"}}}, []int64{2, 3, 5}},
+		{"range", map[string]interface{}{"score": map[string]interface{}{"$gte": 2, "$lt": 4}}, []int64{2, 3}},
+		{"exclusive", map[string]interface{}{"score": map[string]interface{}{"$gt": 2, "$lte": 4}}, []int64{3, 4}},
+		{"ne", map[string]interface{}{"score": map[string]interface{}{"$ne": 3}}, []int64{1, 2, 4, 5}},
+		{"array", map[string]interface{}{"tags": "odd"}, []int64{1, 3, 5}},
+		{"or", map[string]interface{}{"$or": []map[string]interface{}{{"name": "a"}, {"score": 5}}}, []int64{1, 5}},
+		{"missing", map[string]interface{}{"name": "z"}, []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, rows := find(t, r, repo.FindOptions{Params: tt.params})
+			if total != int64(len(tt.want)) {
+				t.Errorf("total = %d, want %d", total, len(tt.want))
+			}
+			assertScores(t, rows, tt.want)
+		})
+	}
+}
+
+func testSoftDelete(t *testing.T, open Open) {
+	ctx := context.Background()
+	r := open(t, map[string]int{"score": 1}, &Delegate{})
+	ids := seed(t, r, "a", "b", "c")
+
+	if err := r.Delete(ctx, ids[1]); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	total, rows := find(t, r, repo.FindOptions{})
+	if total != 2 {
+		t.Errorf("total = %d, want 2", total)
+	}
+	assertScores(t, rows, []int64{1, 3})
+
+	total, rows = find(t, r, repo.FindOptions{IncludeRemoved: true})
+	if total != 3 {
+		t.Errorf("total including removed = %d, want 3", total)
+	}
+	assertScores(t, rows, []int64{1, 2, 3})
+
+	obj, err := r.Get(ctx, ids[1])
+	if err != nil {
+		t.Fatalf("Get() of deleted error = %v", err)
+	}
+	if !obj.(*Doc).Deleted {
+		t.Errorf("Get() of deleted isn't marked deleted")
+	}
+
+	if err := r.Remove(ctx, ids[1]); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := r.Get(ctx, ids[1]); err != mongo.ErrNoDocuments {
+		t.Errorf("Get() of removed error = %v, want %v", err, mongo.ErrNoDocuments)
+	}
+	if err := r.Remove(ctx, ids[1]); err != nil {
+		t.Errorf("Remove() of missing error = %v, want nil", err)
+	}
+	if err := r.Delete(ctx, ids[1]); err != nil {
+		t.Errorf("Delete() of missing error = %v, want nil", err)
+	}
+}
+
+func testDelegates(t *testing.T, open Open) {
+	ctx := context.Background()
+	del := &Delegate{}
+	r := open(t, nil, del)
+
+	doc := &Doc{Name: "a", Score: 1}
+	if err := r.Create(ctx, doc); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(del.Created) != 1 || del.Created[0].IsZero() {
+		t.Fatalf("DidCreate ids = %v, want a generated one", del.Created)
+	}
+
+	id := del.Created[0].Hex()
+	if err := r.Update(ctx, id, &Doc{Score: 2}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if del.Updated != 1 || len(del.Upserted) != 0 {
+		t.Errorf("DidUpdate = %d upserted %v, want 1 without upsert", del.Updated, del.Upserted)
+	}
+
+	obj, err := r.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := obj.(*Doc); got.Name != "a" || got.Score != 2 {
+		t.Errorf("Get() after Update = %+v, want name a and score 2", got)
+	}
+
+	missing := primitive.NewObjectID()
+	if err := r.Update(ctx, missing.Hex(), &Doc{Name: "b"}); err != nil {
+		t.Fatalf("Update() of missing error = %v", err)
+	}
+	if _, err := r.Get(ctx, missing.Hex()); err != mongo.ErrNoDocuments {
+		t.Errorf("Get() of missing updated without upsert error = %v, want %v", err, mongo.ErrNoDocuments)
+	}
+
+	del.Upsert = true
+	if err := r.Update(ctx, missing.Hex(), &Doc{Name: "b"}); err != nil {
+		t.Fatalf("Update() with upsert error = %v", err)
+	}
+	if len(del.Upserted) != 1 || del.Upserted[0] != missing {
+		t.Errorf("DidUpdate upserted = %v, want [%v]", del.Upserted, missing)
+	}
+	if obj, err := r.Get(ctx, missing.Hex()); err != nil || obj.(*Doc).Name != "b" {
+		t.Errorf("Get() of upserted = %+v, %v, want name b", obj, err)
+	}
+}
+
+func testMutate(t *testing.T, open Open) {
+	ctx := context.Background()
+	r := open(t, nil, &Delegate{})
+	ids := seed(t, r, "a")
+
+	obj, err := r.Mutate(ctx, ids[0], repo.Mutation{
+		Inc:  map[string]int64{"score": 4},
+		Set:  map[string]interface{}{"name": "b"},
+		Push: map[string]interface{}{"tags": "new"},
+	})
+	if err != nil {
+		t.Fatalf("Mutate() error = %v", err)
+	}
+	got := obj.(*Doc)
+	if got.Score != 5 || got.Name != "b" || len(got.Tags) != 2 || got.Tags[1] != "new" {
+		t.Errorf("Mutate() = %+v, want score 5, name b and tags [odd new]", got)
+	}
+
+	// mongodb rejects pushing and pulling the same field at once
+	obj, err = r.Mutate(ctx, ids[0], repo.Mutation{Pull: map[string]interface{}{"tags": "odd"}})
+	if err != nil {
+		t.Fatalf("Mutate() error = %v", err)
+	}
+	if got := obj.(*Doc); len(got.Tags) != 1 || got.Tags[0] != "new" {
+		t.Errorf("Mutate() pulling = %+v, want tags [new]", got)
+	}
+
+	stored, err := r.Get(ctx, ids[0])
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.(*Doc).Score != 5 {
+		t.Errorf("Get() after Mutate score = %d, want 5", stored.(*Doc).Score)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		m    repo.Mutation
+		want error
+	}{
+		{"matched", ids[0], repo.Mutation{If: map[string]interface{}{"score": 5}, Inc: map[string]int64{"score": -5}}, nil},
+		{"unmatched", ids[0], repo.Mutation{If: map[string]interface{}{"score": 5}, Inc: map[string]int64{"score": -5}}, repo.ErrUnmatched},
+		{"missing", primitive.NewObjectID().Hex(), repo.Mutation{Inc: map[string]int64{"score": 1}}, mongo.ErrNoDocuments},
+		{"missing with condition", primitive.NewObjectID().Hex(), repo.Mutation{If: map[string]interface{}{"score": 0}}, mongo.ErrNoDocuments},
+	}
+
+	for _, tt := range tests {
+		if _, err := r.Mutate(ctx, tt.id, tt.m); err != tt.want {
+			t.Errorf("%s: Mutate() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func testVersions(t *testing.T, open Open) {
+	ctx := context.Background()
+	r := open(t, nil, &Delegate{})
+	ids := seed(t, r, "a")
+	id := ids[0]
+
+	versionOf := func() int64 {
+		vctx, versions := repo.CollectVersions(ctx)
+		if _, err := r.Get(vctx, id); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		v, ok := versions.Of(id)
+		if !ok {
+			t.Fatalf("version of %s isn't collected", id)
+		}
+		return v
+	}
+
+	if v := versionOf(); v != 0 {
+		t.Errorf("version after Create = %d, want 0", v)
+	}
+	if err := r.Update(ctx, id, &Doc{Score: 2}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if v := versionOf(); v != 1 {
+		t.Errorf("version after Update = %d, want 1", v)
+	}
+	if _, err := r.Mutate(repo.ExpectVersion(ctx, id, 0), id, repo.Mutation{Inc: map[string]int64{"score": 1}}); err != nil {
+		t.Fatalf("Mutate() ignoring expected version error = %v", err)
+	}
+	if v := versionOf(); v != 2 {
+		t.Errorf("version after Mutate = %d, want 2", v)
+	}
+
+	stale := repo.ExpectVersion(ctx, id, 1)
+	if err := r.Update(stale, id, &Doc{Score: 9}); err != repo.ErrVersionMismatch {
+		t.Errorf("Update() of stale version error = %v, want %v", err, repo.ErrVersionMismatch)
+	}
+	if err := r.Delete(stale, id); err != repo.ErrVersionMismatch {
+		t.Errorf("Delete() of stale version error = %v, want %v", err, repo.ErrVersionMismatch)
+	}
+	if err := r.Remove(stale, id); err != repo.ErrVersionMismatch {
+		t.Errorf("Remove() of stale version error = %v, want %v", err, repo.ErrVersionMismatch)
+	}
+
+	if err := r.Update(repo.ExpectVersion(ctx, id, 2), id, &Doc{Score: 9}); err != nil {
+		t.Errorf("Update() of expected version error = %v", err)
+	}
+	if v := versionOf(); v != 3 {
+		t.Errorf("version after expected Update = %d, want 3", v)
+	}
+	if err := r.Delete(repo.ExpectVersion(ctx, id, 3), id); err != nil {
+		t.Errorf("Delete() of expected version error = %v", err)
+	}
+	if err := r.Remove(repo.ExpectVersion(ctx, id, 4), id); err != nil {
+		t.Errorf("Remove() of expected version error = %v", err)
+	}
+
+	missing := primitive.NewObjectID().Hex()
+	if err := r.Update(repo.ExpectVersion(ctx, missing, 0), missing, &Doc{}); err != mongo.ErrNoDocuments {
+		t.Errorf("Update() of missing expected error = %v, want %v", err, mongo.ErrNoDocuments)
+	}
+
+	seed(t, r, "b", "c")
+	vctx, versions := repo.CollectVersions(ctx)
+	find(t, r, repo.FindOptions{Params: map[string]interface{}{}, Page: 1, Size: 10}, vctx)
+	digest := versions.Digest()
+
+	vctx, versions = repo.CollectVersions(ctx)
+	_, rows := find(t, r, repo.FindOptions{Page: 1, Size: 10}, vctx)
+	if versions.Digest() != digest {
+		t.Errorf("Digest() of unchanged objects changed")
+	}
+	if _, err := r.Mutate(ctx, rows[0].ID.Hex(), repo.Mutation{Set: map[string]interface{}{"name": "x"}}); err != nil {
+		t.Fatalf("Mutate() error = %v", err)
+	}
+	vctx, versions = repo.CollectVersions(ctx)
+	find(t, r, repo.FindOptions{Page: 1, Size: 10}, vctx)
+	if versions.Digest() == digest {
+		t.Errorf("Digest() of changed object didn't change")
+	}
+}
+
+func testTransaction(t *testing.T, open Open) {
+	ctx := context.Background()
+	r := open(t, nil, &Delegate{})
+	ids := seed(t, r, "a")
+
+	errAbort := errors.New("abort")
+	err := r.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := r.Create(ctx, &Doc{Name: "b", Score: 2}); err != nil {
+			return err
+		}
+		if _, err := r.Mutate(ctx, ids[0], repo.Mutation{Inc: map[string]int64{"score": 10}}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("WithTransaction() error = %v, want %v", err, errAbort)
+	}
+
+	_, rows := find(t, r, repo.FindOptions{})
+	assertScores(t, rows, []int64{1})
+
+	err = r.WithTransaction(ctx, func(ctx context.Context) error {
+		// nested transaction joins the ongoing one
+		return r.WithTransaction(ctx, func(ctx context.Context) error {
+			return r.Create(ctx, &Doc{Name: "b", Score: 2})
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction() error = %v", err)
+	}
+
+	_, rows = find(t, r, repo.FindOptions{Sort: &repo.SortOption{Field: "score"}})
+	assertScores(t, rows, []int64{1, 2})
+}
+
+// seed a Doc of each name, scored by its position from 1 and tagged odd or even, returns their ids
+func seed(t *testing.T, r Repo, names ...string) []string {
+	t.Helper()
+
+	ids := []string{}
+	for i, name := range names {
+		tag := "odd"
+		if i%2 == 1 {
+			tag = "even"
+		}
+
+		doc := &Doc{ID: primitive.NewObjectID(), Name: name, Score: int64(i + 1), Tags: []string{tag}}
+		if err := r.Create(context.Background(), doc); err != nil {
+			t.Fatalf("Create(%s) error = %v", name, err)
+		}
+		ids = append(ids, doc.ID.Hex())
+	}
+
+	return ids
+}
+
+// find Docs, with ctx if given
+func find(t *testing.T, r Repo, opt repo.FindOptions, ctx ...context.Context) (int64, []*Doc) {
+	t.Helper()
+
+	c := context.Background()
+	if len(ctx) > 0 {
+		c = ctx[0]
+	}
+
+	total, rows, err := r.Find(c, opt)
+	if err != nil {
+		t.Fatalf("Find(%+v) error = %v", opt, err)
+	}
+
+	docs := []*Doc{}
+	for _, row := range rows {
+		docs = append(docs, row.(*Doc))
+	}
+
+	return total, docs
+}
+
+func assertScores(t *testing.T, docs []*Doc, want []int64) {
+	t.Helper()
+
+	got := []int64{}
+	for _, doc := range docs {
+		got = append(got, doc.Score)
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("scores = %v, want %v", got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("scores = %v, want %v", got, want)
+			return
+		}
+	}
+}
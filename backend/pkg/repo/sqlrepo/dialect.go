@@ -0,0 +1,172 @@
+package sqlrepo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Dialect of SQL database, documents are stored as JSON and queried by their fields
+type Dialect interface {
+	// Placeholder of nth argument, starting from 1
+	Placeholder(n int) string
+
+	// Field of document by its path, e.g. ["answer", "choice"]
+	Field(path []string) string
+
+	// Exists is the condition of a field being present, even if null
+	Exists(path []string) string
+
+	// IsNull is the condition of a field being missing or null
+	IsNull(path []string) string
+
+	// Arg of a value to compare with a field, value is already converted into JSON types
+	Arg(v interface{}) interface{}
+
+	// Compare field with argument placeholder
+	Compare(field, op, placeholder string) string
+
+	// Contains is the condition of an array field having an element equal to argument placeholder
+	Contains(path []string, placeholder string) string
+
+	// Literal of a value inlined into statement in place of placeholder, e.g. partial filter of an index
+	Literal(v interface{}) string
+
+	// CreateTable statement of a collection, documents are kept as JSON and raw bson
+	CreateTable(table string) string
+
+	// ListIndexes query of a table, returns name of indexes
+	ListIndexes(table string) (string, []interface{})
+
+	// IsDuplicate is true when err violates unique constraint
+	IsDuplicate(err error) bool
+}
+
+// SQLite dialect, fields are extracted by json_extract
+var SQLite Dialect = &sqlite{}
+
+// Postgres dialect, fields are extracted as jsonb and compared to jsonb
+var Postgres Dialect = &postgres{}
+
+type sqlite struct{}
+
+func (d *sqlite) Placeholder(n int) string {
+	return "?"
+}
+
+func (d *sqlite) Field(path []string) string {
+	return fmt.Sprintf("json_extract(doc, '%s')", d.path(path))
+}
+
+func (d *sqlite) Exists(path []string) string {
+	return fmt.Sprintf("json_type(doc, '%s') IS NOT NULL", d.path(path))
+}
+
+func (d *sqlite) IsNull(path []string) string {
+	return d.Field(path) + " IS NULL"
+}
+
+func (d *sqlite) Arg(v interface{}) interface{} {
+	return v
+}
+
+func (d *sqlite) Compare(field, op, placeholder string) string {
+	return fmt.Sprintf("%s %s %s", field, op, placeholder)
+}
+
+func (d *sqlite) Contains(path []string, placeholder string) string {
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(doc, '%[1]s') WHERE json_type(doc, '%[1]s') = 'array' AND value = %[2]s)",
+		d.path(path), placeholder)
+}
+
+func (d *sqlite) Literal(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return quote(x)
+	case bool:
+		if x {
+			return "1"
+		}
+		return "0"
+	case nil:
+		return "NULL"
+	}
+
+	return fmt.Sprint(v)
+}
+
+func (d *sqlite) CreateTable(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (id TEXT PRIMARY KEY, doc TEXT NOT NULL, raw BLOB NOT NULL)`, table)
+}
+
+func (d *sqlite) ListIndexes(table string) (string, []interface{}) {
+	return "SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", []interface{}{table}
+}
+
+func (d *sqlite) IsDuplicate(err error) bool {
+	e, ok := err.(sqlite3.Error)
+	return ok && (e.ExtendedCode == sqlite3.ErrConstraintUnique || e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+// path of json_extract, segments are quoted
+func (d *sqlite) path(path []string) string {
+	return `$."` + strings.Join(path, `"."`) + `"`
+}
+
+type postgres struct{}
+
+func (d *postgres) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (d *postgres) Field(path []string) string {
+	return fmt.Sprintf("(doc #> '{%s}')", strings.Join(path, ","))
+}
+
+func (d *postgres) Exists(path []string) string {
+	return fmt.Sprintf("(doc #> '{%s}') IS NOT NULL", strings.Join(path, ","))
+}
+
+func (d *postgres) IsNull(path []string) string {
+	return fmt.Sprintf("COALESCE(%s, 'null'::jsonb) = 'null'::jsonb", d.Field(path))
+}
+
+func (d *postgres) Arg(v interface{}) interface{} {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func (d *postgres) Compare(field, op, placeholder string) string {
+	return fmt.Sprintf("%s %s %s::jsonb", field, op, placeholder)
+}
+
+// Contains by jsonb containment, so an embedded document element matches a superset of the argument
+func (d *postgres) Contains(path []string, placeholder string) string {
+	field := d.Field(path)
+	return fmt.Sprintf("(jsonb_typeof(%[1]s) = 'array' AND %[1]s @> jsonb_build_array(%[2]s::jsonb))", field, placeholder)
+}
+
+func (d *postgres) Literal(v interface{}) string {
+	return quote(d.Arg(v).(string))
+}
+
+func (d *postgres) CreateTable(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (id TEXT PRIMARY KEY, doc JSONB NOT NULL, raw BYTEA NOT NULL)`, table)
+}
+
+func (d *postgres) ListIndexes(table string) (string, []interface{}) {
+	return "SELECT indexname FROM pg_indexes WHERE tablename = $1 AND indexname <> $2", []interface{}{table, table + "_pkey"}
+}
+
+func (d *postgres) IsDuplicate(err error) bool {
+	e, ok := err.(*pq.Error)
+	return ok && e.Code == "23505"
+}
+
+// quote string literal
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,218 @@
+package sqlrepo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo/internal/bsonfilter"
+)
+
+// fieldPattern of each segment of a dotted key, as it is inlined into statement
+var fieldPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// timeLayout of dates stored in JSON, fixed width in UTC so it sorts as string
+const timeLayout = "2006-01-02T15:04:05.000Z"
+
+// where clause translated from mongodb query filter, as produced by queryables
+// supports equality, $and, $or, $nor, $eq, $ne, $in, $nin, $gt, $gte, $lt, $lte and $exists
+type where struct {
+	dialect Dialect
+	inline  bool // values are inlined as literal instead of placeholder
+	args    []interface{}
+}
+
+// build where clause of the filter, arguments are collected into args
+func (w *where) build(filter map[string]interface{}) (string, error) {
+	normalized, err := bsonfilter.Normalize(filter)
+	if err != nil {
+		return "", err
+	}
+
+	return w.and(normalized)
+}
+
+// and of every condition of filter, in order of its keys
+func (w *where) and(filter bson.M) (string, error) {
+	keys := make([]string, 0, len(filter))
+	for key := range filter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	clauses := []string{}
+	for _, key := range keys {
+		var clause string
+		var err error
+		switch key {
+		case "$and", "$or", "$nor":
+			clause, err = w.logical(key, filter[key])
+		default:
+			clause, err = w.field(key, filter[key])
+		}
+		if err != nil {
+			return "", err
+		}
+
+		clauses = append(clauses, clause)
+	}
+
+	if len(clauses) == 0 {
+		return "1 = 1", nil
+	}
+	return strings.Join(clauses, " AND "), nil
+}
+
+// logical of $and, $or, $nor over sub filters
+func (w *where) logical(op string, cond interface{}) (string, error) {
+	filters, ok := cond.(primitive.A)
+	if !ok || len(filters) == 0 {
+		return "", fmt.Errorf("%s must be a non-empty array", op)
+	}
+
+	clauses := []string{}
+	for _, f := range filters {
+		clause, err := w.and(bsonfilter.ToMap(f))
+		if err != nil {
+			return "", err
+		}
+		clauses = append(clauses, "("+clause+")")
+	}
+
+	switch op {
+	case "$or":
+		return "(" + strings.Join(clauses, " OR ") + ")", nil
+	case "$nor":
+		return "NOT COALESCE(" + strings.Join(clauses, " OR ") + ", FALSE)", nil
+	}
+	return "(" + strings.Join(clauses, " AND ") + ")", nil
+}
+
+// field condition, either operators or value to equal
+func (w *where) field(key string, cond interface{}) (string, error) {
+	path, err := fieldPath(key)
+	if err != nil {
+		return "", err
+	}
+
+	ops := bsonfilter.ToMap(cond)
+	if len(ops) == 0 || !bsonfilter.IsOperators(ops) {
+		return w.equals(path, cond), nil
+	}
+
+	names := make([]string, 0, len(ops))
+	for op := range ops {
+		names = append(names, op)
+	}
+	sort.Strings(names)
+
+	clauses := []string{}
+	for _, op := range names {
+		arg := ops[op]
+		switch op {
+		case "$eq":
+			clauses = append(clauses, w.equals(path, arg))
+		case "$ne":
+			clauses = append(clauses, "NOT COALESCE("+w.equals(path, arg)+", FALSE)")
+		case "$in", "$nin":
+			args, ok := arg.(primitive.A)
+			if !ok {
+				return "", fmt.Errorf("%s must be an array", op)
+			}
+			in := []string{}
+			for _, a := range args {
+				in = append(in, w.equals(path, a))
+			}
+			clause := "1 = 0"
+			if len(in) > 0 {
+				clause = "(" + strings.Join(in, " OR ") + ")"
+			}
+			if op == "$nin" {
+				clause = "NOT COALESCE(" + clause + ", FALSE)"
+			}
+			clauses = append(clauses, clause)
+		case "$gt", "$gte", "$lt", "$lte":
+			sign := map[string]string{"$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<="}[op]
+			clauses = append(clauses, w.dialect.Compare(w.dialect.Field(path), sign, w.arg(arg)))
+		case "$exists":
+			clause := w.dialect.Exists(path)
+			if !bsonfilter.Truthy(arg) {
+				clause = "NOT (" + clause + ")"
+			}
+			clauses = append(clauses, clause)
+		default:
+			return "", fmt.Errorf("unsupported operator %s", op)
+		}
+	}
+
+	return strings.Join(clauses, " AND "), nil
+}
+
+// equals as mongodb does, array field matches if any of its elements equals, nil matches missing field
+// NOTE: inlined condition, i.e. partial filter of an index, doesn't look into array
+func (w *where) equals(path []string, v interface{}) string {
+	if v == nil {
+		return w.dialect.IsNull(path)
+	}
+
+	clause := w.dialect.Compare(w.dialect.Field(path), "=", w.arg(v))
+	if w.inline {
+		return clause
+	}
+
+	return "(" + clause + " OR " + w.dialect.Contains(path, w.arg(v)) + ")"
+}
+
+// arg placeholder of a value, or its literal if inlined
+func (w *where) arg(v interface{}) string {
+	v = toJSON(v)
+	if w.inline {
+		return w.dialect.Literal(v)
+	}
+
+	w.args = append(w.args, w.dialect.Arg(v))
+	return w.dialect.Placeholder(len(w.args))
+}
+
+// fieldPath of a dotted key, e.g. answer.choice
+func fieldPath(key string) ([]string, error) {
+	path := strings.Split(key, ".")
+	for _, segment := range path {
+		if !fieldPattern.MatchString(segment) {
+			return nil, fmt.Errorf("invalid field %s", key)
+		}
+	}
+
+	return path, nil
+}
+
+// toJSON converts bson value into JSON types, ObjectID into its hex and date into sortable string
+func toJSON(v interface{}) interface{} {
+	switch x := v.(type) {
+	case primitive.ObjectID:
+		return x.Hex()
+	case primitive.DateTime:
+		return time.Unix(0, int64(x)*int64(time.Millisecond)).UTC().Format(timeLayout)
+	case int32:
+		return int64(x)
+	case primitive.A:
+		arr := make([]interface{}, len(x))
+		for i, e := range x {
+			arr[i] = toJSON(e)
+		}
+		return arr
+	case primitive.M, primitive.D:
+		m := map[string]interface{}{}
+		for key, e := range bsonfilter.ToMap(x) {
+			m[key] = toJSON(e)
+		}
+		return m
+	}
+
+	return v
+}
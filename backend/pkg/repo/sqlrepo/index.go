@@ -0,0 +1,185 @@
+package sqlrepo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+)
+
+// WithIndexes declared for the table, created by SyncIndexes
+// index is named after its table since SQL index names are shared by the whole schema
+// NOTE: text index and TTL are not supported, text index is skipped and TTL is ignored
+func (r *Repo) WithIndexes(indexes ...mongorepo.Index) *Repo {
+	r.indexes = append(r.indexes, indexes...)
+	return r
+}
+
+// InspectIndexes of the table against declared indexes, nothing is changed
+// index is verified by its name only, changed spec is not detected as drift
+func (r *Repo) InspectIndexes(ctx context.Context) (*mongorepo.IndexReport, error) {
+	actual, err := r.listIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &mongorepo.IndexReport{
+		Collection: r.table,
+		Created:    []string{},
+		Missing:    []string{},
+		Verified:   []string{},
+		Drifted:    []*mongorepo.Drift{},
+		Unmanaged:  []string{},
+	}
+
+	declared := map[string]bool{}
+	for _, idx := range r.indexes {
+		if text(idx) {
+			continue
+		}
+
+		declared[r.indexName(idx)] = true
+		if actual[r.indexName(idx)] {
+			report.Verified = append(report.Verified, idx.Name)
+		} else {
+			report.Missing = append(report.Missing, idx.Name)
+		}
+	}
+
+	for name := range actual {
+		if !declared[name] {
+			report.Unmanaged = append(report.Unmanaged, name)
+		}
+	}
+
+	return report, nil
+}
+
+// SyncIndexes creates missing indexes, unmanaged ones are only reported
+func (r *Repo) SyncIndexes(ctx context.Context) (*mongorepo.IndexReport, error) {
+	report, err := r.InspectIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, idx := range r.indexes {
+		for _, name := range report.Missing {
+			if idx.Name != name {
+				continue
+			}
+
+			stmt, err := r.createIndex(idx)
+			if err != nil {
+				return report, err
+			}
+			if _, err := r.conn(ctx).ExecContext(ctx, stmt); err != nil {
+				return report, err
+			}
+			report.Created = append(report.Created, name)
+		}
+	}
+	report.Missing = []string{}
+
+	if len(report.Unmanaged) > 0 {
+		log.Warnf("Indexes of %s are unmanaged: %v", report.Collection, report.Unmanaged)
+	}
+	log.Infof("Indexes of %s are synced, created: %v, verified: %v", report.Collection, report.Created, report.Verified)
+
+	return report, nil
+}
+
+// DropIndexes by name as listed, declared index is recreated by next SyncIndexes
+func (r *Repo) DropIndexes(ctx context.Context, names ...string) error {
+	for _, name := range names {
+		if _, err := r.conn(ctx).ExecContext(ctx, fmt.Sprintf(`DROP INDEX IF EXISTS "%s"`, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DropUnmanaged indexes of the table, returns dropped index names
+func (r *Repo) DropUnmanaged(ctx context.Context) ([]string, error) {
+	report, err := r.InspectIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Unmanaged, r.DropIndexes(ctx, report.Unmanaged...)
+}
+
+// listIndexes of the table by name, excluding primary key
+func (r *Repo) listIndexes(ctx context.Context) (map[string]bool, error) {
+	query, args := r.dialect.ListIndexes(r.table)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names[name] = true
+	}
+
+	return names, rows.Err()
+}
+
+// createIndex statement of declared index, partial filter is inlined
+func (r *Repo) createIndex(idx mongorepo.Index) (string, error) {
+	fields := []string{}
+	for _, key := range idx.Keys {
+		path, err := fieldPath(key.Key)
+		if err != nil {
+			return "", err
+		}
+
+		field := r.dialect.Field(path)
+		if direction, _ := key.Value.(int); direction < 0 {
+			field += " DESC"
+		}
+		fields = append(fields, field)
+	}
+
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+
+	stmt := fmt.Sprintf(`CREATE %sINDEX IF NOT EXISTS "%s" ON "%s" (%s)`,
+		unique, r.indexName(idx), r.table, strings.Join(fields, ", "))
+	if len(idx.Partial) > 0 {
+		w := &where{dialect: r.dialect, inline: true}
+		clause, err := w.build(idx.Partial)
+		if err != nil {
+			return "", err
+		}
+		stmt += " WHERE " + clause
+	}
+
+	return stmt, nil
+}
+
+// indexName in the schema
+func (r *Repo) indexName(idx mongorepo.Index) string {
+	return r.table + "_" + idx.Name
+}
+
+// text index has any key of "text"
+func text(idx mongorepo.Index) bool {
+	for _, key := range idx.Keys {
+		if key.Value == "text" {
+			return true
+		}
+	}
+
+	return false
+}
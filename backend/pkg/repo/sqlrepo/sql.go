@@ -0,0 +1,502 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+	"github.com/di-collective/ditebak/backend/pkg/repo/mongorepo"
+)
+
+// Repo abstraction
+// a collection is a table of documents, kept as raw bson to be decoded and as JSON to be queried
+// behaves as mongorepo.Repo, errors are the ones returned by mongodb driver, e.g. mongo.ErrNoDocuments
+type Repo struct {
+	db          *sql.DB
+	dialect     Dialect
+	table       string
+	sort        map[string]int
+	constructor func() interface{}
+	delegates   mongorepo.Event
+	indexes     []mongorepo.Index
+}
+
+// txKey marks context running inside a transaction of a database
+type txKey struct {
+	db *sql.DB
+}
+
+// conn executes statements, either database or transaction
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// New Repo using SQL database, table is created if not exists
+// @db: sql database, repositories sharing transaction must share the database
+// @dialect: SQLite or Postgres
+// @table: name of the table
+// @sort: Please provide a default sort
+// @con: Please provide a function to a constructor/factory which return a pointer to a struct
+// @del: event delegates, the same ones given to mongorepo
+func New(db *sql.DB,
+	dialect Dialect,
+	table string,
+	sort map[string]int,
+	con func() interface{},
+	del mongorepo.Event) *Repo {
+	if _, err := db.Exec(dialect.CreateTable(table)); err != nil {
+		log.Errorf("Failed to create table %s: %v", table, err)
+	}
+
+	return &Repo{
+		db:          db,
+		dialect:     dialect,
+		table:       table,
+		sort:        sort,
+		constructor: con,
+		delegates:   del,
+	}
+}
+
+// Name of the table
+func (r *Repo) Name() string {
+	return r.table
+}
+
+// Get one
+func (r *Repo) Get(ctx context.Context, id string) (interface{}, error) {
+	log.Traceln(r.table, "GET", id)
+
+	dbo := r.constructor()
+	raw, err := r.raw(ctx, id)
+	if err != nil {
+		return dbo, err
+	}
+
+	repo.RecordVersion(ctx, raw)
+	return dbo, bson.Unmarshal(raw, dbo)
+}
+
+// Find multiple
+func (r *Repo) Find(ctx context.Context, opt repo.FindOptions) (int64, []interface{}, error) {
+	trace := fmt.Sprintf("%s %s", r.table, "FIND")
+
+	// 1. set query
+	fi := map[string]interface{}{}
+	for key, value := range opt.Params {
+		fi[key] = value
+	}
+	if !opt.IncludeRemoved {
+		fi["_deleted"] = map[string]bool{"$exists": false}
+	}
+
+	w := &where{dialect: r.dialect}
+	clause, err := w.build(fi)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// 2. set sort
+	orders := []string{}
+	if opt.Sort != nil {
+		order, err := r.order(opt.Sort.Field, sortDirection(opt.Sort))
+		if err != nil {
+			return 0, nil, err
+		}
+		orders = append(orders, order)
+	} else {
+		fields := make([]string, 0, len(r.sort))
+		for field := range r.sort {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			order, err := r.order(field, r.sort[field])
+			if err != nil {
+				return 0, nil, err
+			}
+			orders = append(orders, order)
+		}
+	}
+	orders = append(orders, "id ASC")
+
+	// 3. set paging
+	query := fmt.Sprintf(`SELECT raw FROM "%s" WHERE %s ORDER BY %s`, r.table, clause, strings.Join(orders, ", "))
+	if opt.Size > 0 {
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", opt.Size, opt.Skip())
+	}
+
+	log.Traceln(trace, "Filter:", fi, "Page:", opt.Page, "Skip:", opt.Skip(), "Limit:", opt.Size)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, w.args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	result := []interface{}{} // I don't want null slice, I want empty slice
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return 0, nil, err
+		}
+
+		dbo := r.constructor()
+		if err := bson.Unmarshal(raw, dbo); err != nil {
+			return 0, nil, err
+		}
+		repo.RecordVersion(ctx, raw)
+		result = append(result, dbo)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	var count int64
+	query = fmt.Sprintf(`SELECT COUNT(*) FROM "%s" WHERE %s`, r.table, clause)
+	if err := r.conn(ctx).QueryRowContext(ctx, query, w.args...).Scan(&count); err != nil {
+		return 0, nil, err
+	}
+
+	log.Traceln(trace, "total:", count, "result:", result)
+	return count, result, nil
+}
+
+// Create a new object, _id is generated unless given
+func (r *Repo) Create(ctx context.Context, obj interface{}) error {
+	r.delegates.WillCreate(obj)
+
+	m, err := toDocument(obj)
+	if err != nil {
+		return err
+	}
+
+	id, ok := m["_id"].(primitive.ObjectID)
+	if !ok {
+		id = primitive.NewObjectID()
+		m["_id"] = id
+	}
+
+	if err := r.insert(ctx, m); err != nil {
+		return err
+	}
+
+	r.delegates.DidCreate(obj, id)
+	return nil
+}
+
+// Update an existing object, fields of obj are set on top of the stored ones and its version is incremented
+// object is inserted if not found and delegate sets upsert, unless ctx expects a version of it
+func (r *Repo) Update(ctx context.Context, id string, obj interface{}) error {
+	uo := options.Update()
+	r.delegates.WillUpdate(obj, uo)
+
+	set, err := toDocument(obj)
+	if err != nil {
+		return err
+	}
+
+	var uid *primitive.ObjectID
+	err = r.WithTransaction(ctx, func(ctx context.Context) error {
+		m, err := r.versioned(ctx, id)
+		if err != nil {
+			return err
+		} else if m == nil && uo.Upsert != nil && *uo.Upsert {
+			_id, _ := primitive.ObjectIDFromHex(id)
+			set["_id"] = _id
+			set[repo.VersionField] = int64(1)
+			uid = &_id
+			return r.insert(ctx, set)
+		} else if m == nil {
+			return nil
+		}
+
+		return r.replace(ctx, id, merge(m, nextVersion(m, set)))
+	})
+	if err != nil {
+		return err
+	}
+
+	r.delegates.DidUpdate(obj, uid)
+	return nil
+}
+
+// Delete an existing object virtually, its version is incremented
+func (r *Repo) Delete(ctx context.Context, id string) error {
+	return r.WithTransaction(ctx, func(ctx context.Context) error {
+		m, err := r.versioned(ctx, id)
+		if err != nil || m == nil {
+			return err
+		}
+
+		return r.replace(ctx, id, merge(m, nextVersion(m, bson.M{"_deleted": true})))
+	})
+}
+
+// Remove an existing object physically
+func (r *Repo) Remove(ctx context.Context, id string) error {
+	return r.WithTransaction(ctx, func(ctx context.Context) error {
+		m, err := r.versioned(ctx, id)
+		if err != nil || m == nil {
+			return err
+		}
+
+		query := fmt.Sprintf(`DELETE FROM "%s" WHERE id = %s`, r.table, r.dialect.Placeholder(1))
+		_, err = r.conn(ctx).ExecContext(ctx, query, id)
+		return err
+	})
+}
+
+// WithTransaction runs fn inside a database transaction
+// joins the ongoing transaction if ctx already carries one
+func (r *Repo) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{r.db}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{r.db}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Mutate fields of an existing object atomically and return the object after mutation, its version is incremented
+// condition is checked by the database, mutation is applied on the decoded document
+func (r *Repo) Mutate(ctx context.Context, id string, m repo.Mutation) (interface{}, error) {
+	log.Traceln(r.table, "MUTATE", id, m)
+
+	dbo := r.constructor()
+	err := r.WithTransaction(ctx, func(ctx context.Context) error {
+		doc, err := r.locked(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if len(m.If) > 0 {
+			w := &where{dialect: r.dialect, args: []interface{}{id}}
+			clause, err := w.build(m.If)
+			if err != nil {
+				return err
+			}
+
+			var n int64
+			query := fmt.Sprintf(`SELECT COUNT(*) FROM "%s" WHERE id = %s AND %s`, r.table, r.dialect.Placeholder(1), clause)
+			if err := r.conn(ctx).QueryRowContext(ctx, query, w.args...).Scan(&n); err != nil {
+				return err
+			}
+			if n == 0 {
+				return repo.ErrUnmatched
+			}
+		}
+
+		mutated, err := mongorepo.Apply(doc, m.Versioned())
+		if err != nil {
+			return err
+		}
+		if err := r.replace(ctx, id, mutated); err != nil {
+			return err
+		}
+
+		raw, err := bson.Marshal(mutated)
+		if err != nil {
+			return err
+		}
+		return bson.Unmarshal(raw, dbo)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dbo, nil
+}
+
+// conn of ctx, transaction if ctx carries one
+func (r *Repo) conn(ctx context.Context) conn {
+	if tx, ok := ctx.Value(txKey{r.db}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return r.db
+}
+
+// raw bson of a document, mongo.ErrNoDocuments if not found
+func (r *Repo) raw(ctx context.Context, id string) ([]byte, error) {
+	var raw []byte
+	query := fmt.Sprintf(`SELECT raw FROM "%s" WHERE id = %s`, r.table, r.dialect.Placeholder(1))
+	err := r.conn(ctx).QueryRowContext(ctx, query, id).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return raw, err
+}
+
+// document of the id as map, mongo.ErrNoDocuments if not found
+func (r *Repo) document(ctx context.Context, id string) (bson.M, error) {
+	raw, err := r.raw(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m := bson.M{}
+	return m, bson.Unmarshal(raw, &m)
+}
+
+// locked document of the id, mongo.ErrNoDocuments if not found
+// the row is locked by a no-op update first so concurrent writers wait instead of overwriting each other
+func (r *Repo) locked(ctx context.Context, id string) (bson.M, error) {
+	query := fmt.Sprintf(`UPDATE "%s" SET id = id WHERE id = %s`, r.table, r.dialect.Placeholder(1))
+	if _, err := r.conn(ctx).ExecContext(ctx, query, id); err != nil {
+		return nil, err
+	}
+
+	return r.document(ctx, id)
+}
+
+// versioned document of the id as locked, repo.ErrVersionMismatch if it isn't of the version expected by ctx
+// missing document is nil without error, unless ctx expects a version of it
+func (r *Repo) versioned(ctx context.Context, id string) (bson.M, error) {
+	m, err := r.locked(ctx, id)
+	expected, ok := repo.ExpectedVersion(ctx, id)
+	switch {
+	case err == mongo.ErrNoDocuments && !ok:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case ok && expected != version(m):
+		return nil, repo.ErrVersionMismatch
+	}
+
+	return m, nil
+}
+
+// insert a new document
+func (r *Repo) insert(ctx context.Context, m bson.M) error {
+	id, _ := m["_id"].(primitive.ObjectID)
+	raw, doc, err := encode(m)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO "%s" (id, doc, raw) VALUES (%s, %s, %s)`,
+		r.table, r.dialect.Placeholder(1), r.dialect.Placeholder(2), r.dialect.Placeholder(3))
+	_, err = r.conn(ctx).ExecContext(ctx, query, id.Hex(), doc, raw)
+	return r.translate(err)
+}
+
+// replace document of the id
+func (r *Repo) replace(ctx context.Context, id string, m bson.M) error {
+	raw, doc, err := encode(m)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE "%s" SET doc = %s, raw = %s WHERE id = %s`,
+		r.table, r.dialect.Placeholder(1), r.dialect.Placeholder(2), r.dialect.Placeholder(3))
+	_, err = r.conn(ctx).ExecContext(ctx, query, doc, raw, id)
+	return r.translate(err)
+}
+
+// translate unique constraint violation into duplicate key error as returned by mongodb
+func (r *Repo) translate(err error) error {
+	if err != nil && r.dialect.IsDuplicate(err) {
+		return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+			Code:    11000,
+			Message: fmt.Sprintf("E11000 duplicate key error collection: %s: %v", r.table, err),
+		}}}
+	}
+
+	return err
+}
+
+// order by a field
+func (r *Repo) order(field string, direction int) (string, error) {
+	path, err := fieldPath(field)
+	if err != nil {
+		return "", err
+	}
+
+	if direction < 0 {
+		return r.dialect.Field(path) + " DESC NULLS LAST", nil
+	}
+	return r.dialect.Field(path) + " ASC NULLS FIRST", nil
+}
+
+// toDocument of any object marshallable into bson
+func toDocument(obj interface{}) (bson.M, error) {
+	raw, err := bson.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	m := bson.M{}
+	return m, bson.Unmarshal(raw, &m)
+}
+
+// encode document as raw bson and JSON
+func encode(m bson.M) ([]byte, string, error) {
+	raw, err := bson.Marshal(m)
+	if err != nil {
+		return nil, "", err
+	}
+
+	doc, err := json.Marshal(toJSON(m))
+	return raw, string(doc), err
+}
+
+// version of a document, 0 if it has none
+func version(m bson.M) int64 {
+	switch v := m[repo.VersionField].(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	}
+
+	return 0
+}
+
+// nextVersion of doc along with fields of set, as $inc does
+func nextVersion(doc, set bson.M) bson.M {
+	return merge(set, bson.M{repo.VersionField: version(doc) + 1})
+}
+
+// merge fields of set on top of doc as $set does
+func merge(doc, set bson.M) bson.M {
+	merged := bson.M{}
+	for key, value := range doc {
+		merged[key] = value
+	}
+	for key, value := range set {
+		merged[key] = value
+	}
+
+	return merged
+}
+
+func sortDirection(opt *repo.SortOption) int {
+	if opt.Descending {
+		return -1
+	}
+
+	return 1
+}
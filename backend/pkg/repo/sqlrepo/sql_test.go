@@ -0,0 +1,56 @@
+package sqlrepo_test
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo/repotest"
+	"github.com/di-collective/ditebak/backend/pkg/repo/sqlrepo"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T, sort map[string]int, del *repotest.Delegate) repotest.Repo {
+		return sqlrepo.New(openSQLite(t), sqlrepo.SQLite, "docs", sort, repotest.NewDoc, del)
+	})
+}
+
+// TestConformancePostgres runs against POSTGRES_DSN, skipped without it
+func TestConformancePostgres(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	n := 0
+	repotest.Run(t, func(t *testing.T, sort map[string]int, del *repotest.Delegate) repotest.Repo {
+		n++
+		table := fmt.Sprintf("repotest_%d_%d", os.Getpid(), n)
+		t.Cleanup(func() { db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS "%s"`, table)) })
+
+		return sqlrepo.New(db, sqlrepo.Postgres, table, sort, repotest.NewDoc, del)
+	})
+}
+
+// openSQLite database in a temporary file, closed when the test ends
+func openSQLite(t *testing.T) *sql.DB {
+	dsn := filepath.Join(t.TempDir(), "repo.db") + "?_busy_timeout=5000&_journal_mode=WAL"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
@@ -0,0 +1,229 @@
+// Package typed is the type-safe counterpart of repo,
+// untyped repositories are adapted into it and back while resources migrate
+package typed
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+)
+
+// Repository of T, P is the payload of update, usually T itself or a partial dto of it
+type Repository[T, P any] interface {
+	Reader[T]
+	Writer[T, P]
+}
+
+// Reader of T
+type Reader[T any] interface {
+	// Get one
+	Get(ctx context.Context, id string) (T, error)
+
+	// Find multiple
+	Find(ctx context.Context, opt repo.FindOptions) (total int64, rows []T, err error)
+}
+
+// Writer of T, updated by P
+type Writer[T, P any] interface {
+	// Create a new object
+	Create(ctx context.Context, obj T) error
+
+	// Update an existing object
+	Update(ctx context.Context, id string, obj P) error
+
+	// Delete an existing object virtually
+	Delete(ctx context.Context, id string) error
+
+	// Remove an existing object physically
+	Remove(ctx context.Context, id string) error
+}
+
+// Event delegates of T, updated by P
+type Event[T, P any] interface {
+	Constructor() T
+	WillCreate(T)
+	DidCreate(T, primitive.ObjectID)
+
+	WillUpdate(P, *options.UpdateOptions)
+
+	// @obj: is the object being updated
+	// @upsert: is the ID of object if upsert is done. nil if no upsert
+	DidUpdate(obj P, upsert *primitive.ObjectID)
+}
+
+// Of untyped repository, object of another type is an error instead of a panic
+func Of[T, P any](rps repo.Repository) Repository[T, P] {
+	return &typedRepo[T, P]{rps: rps}
+}
+
+// Untyped repository of a typed one, payload of another type is an error instead of a panic
+func Untyped[T, P any](rps Repository[T, P]) repo.Repository {
+	return &untypedRepo[T, P]{rps: rps}
+}
+
+// Delegate of untyped repository from typed event delegates
+// e.g. mongorepo.New(coll, sort, del.Constructor, del) with del := typed.Delegate(&delegate{})
+func Delegate[T, P any](del Event[T, P]) *Delegates[T, P] {
+	return &Delegates[T, P]{del: del}
+}
+
+// Cast v into T, as rows of untyped repository or payloads of untyped service
+func Cast[T any](v interface{}) (T, error) {
+	t, ok := v.(T)
+	if !ok {
+		return t, fmt.Errorf("expected %T but got %T", t, v)
+	}
+
+	return t, nil
+}
+
+// typedRepo adapts untyped repository
+type typedRepo[T, P any] struct {
+	rps repo.Repository
+}
+
+func (r *typedRepo[T, P]) Get(ctx context.Context, id string) (T, error) {
+	v, err := r.rps.Get(ctx, id)
+	if err != nil {
+		t, _ := v.(T)
+		return t, err
+	}
+
+	return Cast[T](v)
+}
+
+func (r *typedRepo[T, P]) Find(ctx context.Context, opt repo.FindOptions) (int64, []T, error) {
+	total, rows, err := r.rps.Find(ctx, opt)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	result := make([]T, 0, len(rows))
+	for _, row := range rows {
+		t, err := Cast[T](row)
+		if err != nil {
+			return 0, nil, err
+		}
+		result = append(result, t)
+	}
+
+	return total, result, nil
+}
+
+func (r *typedRepo[T, P]) Create(ctx context.Context, obj T) error {
+	return r.rps.Create(ctx, obj)
+}
+
+func (r *typedRepo[T, P]) Update(ctx context.Context, id string, obj P) error {
+	return r.rps.Update(ctx, id, obj)
+}
+
+func (r *typedRepo[T, P]) Delete(ctx context.Context, id string) error {
+	return r.rps.Delete(ctx, id)
+}
+
+func (r *typedRepo[T, P]) Remove(ctx context.Context, id string) error {
+	return r.rps.Remove(ctx, id)
+}
+
+// untypedRepo adapts typed repository
+type untypedRepo[T, P any] struct {
+	rps Repository[T, P]
+}
+
+func (r *untypedRepo[T, P]) Get(ctx context.Context, id string) (interface{}, error) {
+	return r.rps.Get(ctx, id)
+}
+
+func (r *untypedRepo[T, P]) Find(ctx context.Context, opt repo.FindOptions) (int64, []interface{}, error) {
+	total, rows, err := r.rps.Find(ctx, opt)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	result := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, row)
+	}
+
+	return total, result, nil
+}
+
+func (r *untypedRepo[T, P]) Create(ctx context.Context, obj interface{}) error {
+	t, err := Cast[T](obj)
+	if err != nil {
+		return err
+	}
+
+	return r.rps.Create(ctx, t)
+}
+
+func (r *untypedRepo[T, P]) Update(ctx context.Context, id string, obj interface{}) error {
+	p, err := Cast[P](obj)
+	if err != nil {
+		return err
+	}
+
+	return r.rps.Update(ctx, id, p)
+}
+
+func (r *untypedRepo[T, P]) Delete(ctx context.Context, id string) error {
+	return r.rps.Delete(ctx, id)
+}
+
+func (r *untypedRepo[T, P]) Remove(ctx context.Context, id string) error {
+	return r.rps.Remove(ctx, id)
+}
+
+// Delegates adapts typed event delegates into untyped ones, i.e. mongorepo.Event
+// object of another type skips the delegate with an error log instead of a panic
+type Delegates[T, P any] struct {
+	del Event[T, P]
+}
+
+// Constructor of T
+func (d *Delegates[T, P]) Constructor() interface{} {
+	return d.del.Constructor()
+}
+
+// WillCreate T
+func (d *Delegates[T, P]) WillCreate(data interface{}) {
+	if t, err := Cast[T](data); err != nil {
+		log.Errorln("Skipped WillCreate delegate:", err)
+	} else {
+		d.del.WillCreate(t)
+	}
+}
+
+// DidCreate T
+func (d *Delegates[T, P]) DidCreate(created interface{}, id primitive.ObjectID) {
+	if t, err := Cast[T](created); err != nil {
+		log.Errorln("Skipped DidCreate delegate:", err)
+	} else {
+		d.del.DidCreate(t, id)
+	}
+}
+
+// WillUpdate by P
+func (d *Delegates[T, P]) WillUpdate(data interface{}, opt *options.UpdateOptions) {
+	if p, err := Cast[P](data); err != nil {
+		log.Errorln("Skipped WillUpdate delegate:", err)
+	} else {
+		d.del.WillUpdate(p, opt)
+	}
+}
+
+// DidUpdate by P
+func (d *Delegates[T, P]) DidUpdate(data interface{}, upsert *primitive.ObjectID) {
+	if p, err := Cast[P](data); err != nil {
+		log.Errorln("Skipped DidUpdate delegate:", err)
+	} else {
+		d.del.DidUpdate(p, upsert)
+	}
+}
@@ -0,0 +1,126 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VersionField of every document, incremented by every write
+// document written before it was maintained has none, its version is 0
+const VersionField = "_version"
+
+// ErrVersionMismatch is returned by Writer when the object isn't of the version expected by ctx
+var ErrVersionMismatch = errors.New("object isn't of the expected version")
+
+type expectedKey struct{}
+
+type versionsKey struct{}
+
+// header of a document, identifies its version
+type header struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	Version int64              `bson:"_version"`
+}
+
+// expected version of an object
+type expected struct {
+	id      string
+	version int64
+}
+
+// ExpectVersion of the object with the id, its Update, Delete or Remove with the returned ctx
+// fails with ErrVersionMismatch if it is of another version, other objects written with ctx are not affected
+func ExpectVersion(ctx context.Context, id string, version int64) context.Context {
+	return context.WithValue(ctx, expectedKey{}, &expected{id: id, version: version})
+}
+
+// ExpectedVersion of the object with the id by ctx, false if none is expected
+func ExpectedVersion(ctx context.Context, id string) (int64, bool) {
+	exp, ok := ctx.Value(expectedKey{}).(*expected)
+	if !ok || exp.id != id {
+		return 0, false
+	}
+
+	return exp.version, true
+}
+
+// Versions of objects read by Get and Find
+type Versions struct {
+	mu       sync.Mutex
+	ids      []string
+	versions map[string]int64
+}
+
+// CollectVersions of objects read with the returned ctx
+func CollectVersions(ctx context.Context) (context.Context, *Versions) {
+	v := &Versions{versions: map[string]int64{}}
+	return context.WithValue(ctx, versionsKey{}, v), v
+}
+
+// RecordVersion of a raw bson document read with ctx, called by repositories
+func RecordVersion(ctx context.Context, raw []byte) {
+	v, ok := ctx.Value(versionsKey{}).(*Versions)
+	if !ok {
+		return
+	}
+
+	h := header{}
+	if err := bson.Unmarshal(raw, &h); err != nil {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	id := h.ID.Hex()
+	if _, ok := v.versions[id]; !ok {
+		v.ids = append(v.ids, id)
+	}
+	v.versions[id] = h.Version
+}
+
+// Of the object with the id, false if it wasn't read
+func (v *Versions) Of(id string) (int64, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	version, ok := v.versions[id]
+	return version, ok
+}
+
+// Digest of every version in order of reading, changes when any object read changes
+func (v *Versions) Digest() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	h := fnv.New64a()
+	for _, id := range v.ids {
+		fmt.Fprintf(h, "%s:%d;", id, v.versions[id])
+	}
+
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// VersionOf a raw bson document, 0 if it has none
+func VersionOf(raw []byte) int64 {
+	h := header{}
+	bson.Unmarshal(raw, &h)
+
+	return h.Version
+}
+
+// Versioned mutation, version of the object is incremented along with its fields
+func (m Mutation) Versioned() Mutation {
+	inc := map[string]int64{VersionField: 1}
+	for field, delta := range m.Inc {
+		inc[field] = delta
+	}
+	m.Inc = inc
+
+	return m
+}
@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/di-collective/ditebak/backend/pkg/global"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Verbs of generic REST API, used as key of Policy
+const (
+	VerbFind   = "FIND"
+	VerbGet    = "GET"
+	VerbCreate = "CREATE"
+	VerbUpdate = "UPDATE"
+	VerbDelete = "DELETE"
+	VerbRemove = "REMOVE"
+)
+
+// Identity of requester resolved from session
+type Identity struct {
+	Email string
+	Roles []string
+}
+
+// HasRole check whether identity has any of given roles
+func (id *Identity) HasRole(roles ...string) bool {
+	if id == nil {
+		return false
+	}
+
+	for _, have := range id.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Authenticator resolves identity of HTTP request
+// returns nil identity without error if request doesn't carry any session
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// Rule of authorization on a verb
+type Rule struct {
+	Public bool     // anyone can access, even without session
+	Roles  []string // identity must have one of roles, empty means any authenticated identity
+}
+
+// Policy of authorization per verb
+// nil policy allows everything, verb without rule is forbidden
+type Policy map[string]*Rule
+
+// Public rule, no session required
+func Public() *Rule {
+	return &Rule{Public: true}
+}
+
+// Authenticated rule, any valid session is allowed
+func Authenticated() *Rule {
+	return &Rule{}
+}
+
+// Roles rule, session must have one of the roles
+func Roles(roles ...string) *Rule {
+	return &Rule{Roles: roles}
+}
+
+// allows identity to access
+func (rule *Rule) allows(id *Identity) bool {
+	if rule.Public {
+		return true
+	}
+	if id == nil {
+		return false
+	}
+	if len(rule.Roles) == 0 {
+		return true
+	}
+
+	return id.HasRole(rule.Roles...)
+}
+
+// WithIdentity put identity into context
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	ctx = context.WithValue(ctx, global.Context.Identity(), id)
+	return context.WithValue(ctx, global.Context.Email(), id.Email)
+}
+
+// IdentityOf requester stored in context, nil if anonymous
+func IdentityOf(ctx context.Context) *Identity {
+	id, _ := ctx.Value(global.Context.Identity()).(*Identity)
+	return id
+}
+
+// Authorize HTTP handler based on rule
+// responds 401 if session is required but missing, 403 if identity lacks the role
+func Authorize(auth Authenticator, rule *Rule, next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		res := NewAPIResponse(w, r)
+
+		var id *Identity
+		var err error
+		if auth != nil {
+			id, err = auth.Authenticate(r)
+		}
+
+		switch {
+		case rule == nil:
+			res.Error("You are not authorized to access this resource", nil).Respond(http.StatusForbidden)
+			return
+		case !rule.Public && (id == nil || err != nil):
+			res.Error("You are not authenticated", err).Respond(http.StatusUnauthorized)
+			return
+		case !rule.allows(id):
+			res.Error("You are not authorized to access this resource", nil).Respond(http.StatusForbidden)
+			return
+		}
+
+		if id != nil && err == nil {
+			r = r.WithContext(WithIdentity(r.Context(), id))
+		}
+		next(w, r, p)
+	}
+}
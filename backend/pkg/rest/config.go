@@ -3,6 +3,7 @@ package rest
 import (
 	"github.com/di-collective/ditebak/backend/pkg/queryables"
 	"github.com/di-collective/ditebak/backend/pkg/service"
+	"github.com/di-collective/ditebak/backend/pkg/service/typed"
 )
 
 // Config of REST API
@@ -11,7 +12,65 @@ type Config struct {
 	Queryables queryables.Collection
 	Service    service.Service
 
+	Auth   Authenticator // resolves identity of requester
+	Policy Policy        // authorization per verb, nil means no authorization
+
 	CreatePayload func() interface{}            // constructor of HTTP request payload for CREATE
 	UpdatePayload func() interface{}            // constructor of HTTP request payload for UPDATE
 	Convert       func(interface{}) interface{} // convert HTTP request payload to service payload
 }
+
+// Typed config of REST API, payloads are checked against service at compile time
+// T is the payload of CREATE, P is the payload of UPDATE, usually T itself or a partial dto of it
+type Typed[T, P any] struct {
+	Resource   string
+	Queryables queryables.Collection
+	Service    typed.Service[T, P]
+
+	Auth   Authenticator // resolves identity of requester
+	Policy Policy        // authorization per verb, nil means no authorization
+
+	CreatePayload func() T // constructor of HTTP request payload for CREATE
+	UpdatePayload func() P // constructor of HTTP request payload for UPDATE
+}
+
+// Untyped config of the typed one, service is adapted and payloads are given as is
+func (conf *Typed[T, P]) Untyped() *Config {
+	return &Config{
+		Resource:   conf.Resource,
+		Queryables: conf.Queryables,
+		Service:    typed.Untyped(conf.Service),
+		Auth:       conf.Auth,
+		Policy:     conf.Policy,
+		CreatePayload: func() interface{} {
+			return conf.CreatePayload()
+		},
+		UpdatePayload: func() interface{} {
+			return conf.UpdatePayload()
+		},
+	}
+}
+
+// Converted config of REST API, payload C of CREATE is converted into T of the service,
+// e.g. a create dto without fields the service must not take from requester. C must be another type than P
+type Converted[C, T, P any] struct {
+	Typed[T, P]
+	CreatePayload func() C  // constructor of HTTP request payload for CREATE
+	Convert       func(C) T // convert HTTP request payload of CREATE to service payload
+}
+
+// Untyped config of the converted one, payload of UPDATE is given as is
+func (conf *Converted[C, T, P]) Untyped() *Config {
+	untyped := conf.Typed.Untyped()
+	untyped.CreatePayload = func() interface{} {
+		return conf.CreatePayload()
+	}
+	untyped.Convert = func(payload interface{}) interface{} {
+		if c, ok := payload.(C); ok {
+			return conf.Convert(c)
+		}
+		return payload
+	}
+
+	return untyped
+}
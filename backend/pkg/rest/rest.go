@@ -9,6 +9,7 @@ import (
 
 	"github.com/di-collective/ditebak/backend/pkg/exception"
 	"github.com/di-collective/ditebak/backend/pkg/queryables"
+	"github.com/di-collective/ditebak/backend/pkg/repo"
 	"github.com/di-collective/ditebak/backend/pkg/service"
 	"github.com/julienschmidt/httprouter"
 )
@@ -23,6 +24,8 @@ type rest struct {
 	resource   string
 	queryables queryables.Collection
 	service    service.Service
+	auth       Authenticator
+	policy     Policy
 
 	create  func() interface{}            // constructor of HTTP request payload - CREATE
 	update  func() interface{}            // constructor of HTTP request payload - UPDATE
@@ -35,6 +38,8 @@ func New(conf *Config) REST {
 		resource:   conf.Resource,
 		queryables: conf.Queryables,
 		service:    conf.Service,
+		auth:       conf.Auth,
+		policy:     conf.Policy,
 		create:     conf.CreatePayload,
 		update:     conf.UpdatePayload,
 		convert:    conf.Convert,
@@ -43,6 +48,16 @@ func New(conf *Config) REST {
 	return api
 }
 
+// NewTyped REST API
+func NewTyped[T, P any](conf *Typed[T, P]) REST {
+	return New(conf.Untyped())
+}
+
+// NewConverted REST API
+func NewConverted[C, T, P any](conf *Converted[C, T, P]) REST {
+	return New(conf.Untyped())
+}
+
 // NewRouter initialize routes using julienschmidth httprouter
 func (api *rest) NewRouter() *httprouter.Router {
 	router := httprouter.New()
@@ -57,17 +72,26 @@ func (api *rest) WithRouter(router *httprouter.Router) {
 	root := path.Join("/", res)
 	withID := path.Join(root, ":id")
 
-	router.GET(root, api.Find)
-	router.POST(root, api.Create)
-	router.GET(withID, api.Get)
-	router.PATCH(withID, api.Update)
-	router.DELETE(withID, api.Delete)
-	router.Handle("REMOVE", withID, api.Remove)
+	router.GET(root, api.guard(VerbFind, api.Find))
+	router.POST(root, api.guard(VerbCreate, api.Create))
+	router.GET(withID, api.guard(VerbGet, api.Get))
+	router.PATCH(withID, api.guard(VerbUpdate, api.Update))
+	router.DELETE(withID, api.guard(VerbDelete, api.Delete))
+	router.Handle("REMOVE", withID, api.guard(VerbRemove, api.Remove))
+}
+
+// guard handler with authorization policy of the verb
+func (api *rest) guard(verb string, next httprouter.Handle) httprouter.Handle {
+	if api.policy == nil {
+		return next
+	}
+
+	return Authorize(api.auth, api.policy[verb], next)
 }
 
 // Find multiple
 func (api *rest) Find(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-	ctx := r.Context()
+	ctx, versions := repo.CollectVersions(r.Context())
 	res := NewAPIResponse(w, r)
 	page, size := getPageAndSize(r)
 
@@ -83,6 +107,8 @@ func (api *rest) Find(w http.ResponseWriter, r *http.Request, p httprouter.Param
 		return
 	}
 
+	// list is tagged by versions of its rows
+	w.Header().Set("ETag", "W/"+strconv.Quote(versions.Digest()))
 	res.Paging(total, totalPage(total, int64(size))).
 		Payload(result).
 		Respond(http.StatusOK)
@@ -91,7 +117,7 @@ func (api *rest) Find(w http.ResponseWriter, r *http.Request, p httprouter.Param
 // Get one
 func (api *rest) Get(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	id := p.ByName("id")
-	ctx := r.Context()
+	ctx, versions := repo.CollectVersions(r.Context())
 	res := NewAPIResponse(w, r)
 
 	result, err := api.service.Get(ctx, id)
@@ -105,6 +131,9 @@ func (api *rest) Get(w http.ResponseWriter, r *http.Request, p httprouter.Params
 		return
 	}
 
+	if version, ok := versions.Of(id); ok {
+		w.Header().Set("ETag", ETag(version))
+	}
 	res.Payload(result).Respond(http.StatusOK)
 }
 
@@ -142,15 +171,19 @@ func (api *rest) Create(w http.ResponseWriter, r *http.Request, p httprouter.Par
 	res.Payload(result).Respond(http.StatusOK)
 }
 
-// Update one
+// Update one, If-Match is honoured
 func (api *rest) Update(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	id := p.ByName("id")
-	ctx := r.Context()
 	res := NewAPIResponse(w, r)
+	ctx, err := expect(r, id)
+	if exc, throw := exception.IsException(err); throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	}
 
 	// initialize payload struct and parse HTTP request to it
 	httpPayload := api.update()
-	err := ParseBody(r, httpPayload)
+	err = ParseBody(r, httpPayload)
 	if err != nil {
 		res.Error("Failed to parse payload", err).Respond(http.StatusBadRequest)
 		return
@@ -174,16 +207,21 @@ func (api *rest) Update(w http.ResponseWriter, r *http.Request, p httprouter.Par
 		return
 	}
 
+	setETag(ctx, w, id)
 	res.Payload(result).Respond(http.StatusOK)
 }
 
-// Delete one
+// Delete one, If-Match is honoured
 func (api *rest) Delete(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	id := p.ByName("id")
-	ctx := r.Context()
 	res := NewAPIResponse(w, r)
+	ctx, err := expect(r, id)
+	if exc, throw := exception.IsException(err); throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	}
 
-	err := api.service.Delete(ctx, id)
+	err = api.service.Delete(ctx, id)
 	exc, throw := exception.IsException(err)
 	if throw {
 		res.Error(exc.Message(), err).Respond(exc.Code())
@@ -194,16 +232,21 @@ func (api *rest) Delete(w http.ResponseWriter, r *http.Request, p httprouter.Par
 		return
 	}
 
+	setETag(ctx, w, id)
 	res.Respond(http.StatusResetContent)
 }
 
-// Remove one physically
+// Remove one physically, If-Match is honoured
 func (api *rest) Remove(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 	id := p.ByName("id")
-	ctx := r.Context()
 	res := NewAPIResponse(w, r)
+	ctx, err := expect(r, id)
+	if exc, throw := exception.IsException(err); throw {
+		res.Error(exc.Message(), err).Respond(exc.Code())
+		return
+	}
 
-	err := api.service.Remove(ctx, id)
+	err = api.service.Remove(ctx, id)
 	exc, throw := exception.IsException(err)
 	if throw {
 		res.Error(exc.Message(), err).Respond(exc.Code())
@@ -214,6 +257,7 @@ func (api *rest) Remove(w http.ResponseWriter, r *http.Request, p httprouter.Par
 		return
 	}
 
+	setETag(ctx, w, id)
 	res.Respond(http.StatusResetContent)
 }
 
@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/di-collective/ditebak/backend/pkg/exception"
+	"github.com/di-collective/ditebak/backend/pkg/repo"
+)
+
+// ETag of an object version, strong since version changes on every write
+func ETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ParseETag into object version, weak tag is accepted as its version
+func ParseETag(tag string) (int64, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(unquoted, 10, 64)
+}
+
+// expect version of If-Match header for the object with the id
+// request without If-Match or with "*" writes any version
+func expect(r *http.Request, id string) (context.Context, error) {
+	ctx := r.Context()
+	match := strings.TrimSpace(r.Header.Get("If-Match"))
+	if match == "" || match == "*" {
+		return ctx, nil
+	}
+
+	version, err := ParseETag(match)
+	if err != nil {
+		return ctx, exception.New(http.StatusPreconditionFailed, "If-Match must be a single ETag of [%s], got %s", id, match)
+	}
+
+	return repo.ExpectVersion(ctx, id, version), nil
+}
+
+// setETag of the object written as expected, its version is the next one
+func setETag(ctx context.Context, w http.ResponseWriter, id string) {
+	if version, ok := repo.ExpectedVersion(ctx, id); ok {
+		w.Header().Set("ETag", ETag(version+1))
+	}
+}
@@ -47,34 +47,68 @@ func (svc *Service) Find(ctx context.Context, page, size int, opt map[string]int
 	return svc.rps.Find(ctx, fo)
 }
 
-// Create a new object
+// Create a new object, violation of unique index is a conflict
 func (svc *Service) Create(ctx context.Context, obj interface{}) (interface{}, error) {
 	err := svc.rps.Create(ctx, obj)
-	if mwe, ok := err.(mongo.WriteException); ok {
-		if len(mwe.WriteErrors) > 0 {
-			e := mwe.WriteErrors[0]
-			if e.Code == 11000 {
-				return nil, exception.New(http.StatusConflict, "Duplicate resource already exists")
-			}
-		}
+	if isDuplicate(err) {
+		return nil, exception.New(http.StatusConflict, "Duplicate resource already exists")
+	} else if err != nil {
 		return nil, err
 	}
 
-	return obj, err
+	return obj, nil
 }
 
-// Update an existing object
+// Update an existing object, object of another version than expected by ctx is a failed precondition
 func (svc *Service) Update(ctx context.Context, id string, obj interface{}) (interface{}, error) {
 	err := svc.rps.Update(ctx, id, obj)
-	return obj, err
+	return obj, versioned(id, err)
 }
 
 // Delete an existing object virtually
 func (svc *Service) Delete(ctx context.Context, id string) error {
-	return svc.rps.Delete(ctx, id)
+	return versioned(id, svc.rps.Delete(ctx, id))
 }
 
 // Remove an existing object physically
 func (svc *Service) Remove(ctx context.Context, id string) error {
-	return svc.rps.Remove(ctx, id)
+	return versioned(id, svc.rps.Remove(ctx, id))
+}
+
+// versioned write error, only returned when ctx expects a version of the object
+func versioned(id string, err error) error {
+	switch err {
+	case repo.ErrVersionMismatch:
+		return exception.New(http.StatusPreconditionFailed, "Resource with ID: %s, has been modified", id)
+	case mongo.ErrNoDocuments:
+		return exception.New(http.StatusNotFound, "Resource with ID: %s, is not found", id)
+	}
+
+	return err
+}
+
+// isDuplicate key error, returned as write, bulk write or command error depending on the operation
+func isDuplicate(err error) bool {
+	duplicate := func(code int) bool {
+		return code == 11000 || code == 11001 || code == 12582
+	}
+
+	switch e := err.(type) {
+	case mongo.WriteException:
+		for _, we := range e.WriteErrors {
+			if duplicate(we.Code) {
+				return true
+			}
+		}
+	case mongo.BulkWriteException:
+		for _, we := range e.WriteErrors {
+			if duplicate(we.Code) {
+				return true
+			}
+		}
+	case mongo.CommandError:
+		return duplicate(int(e.Code))
+	}
+
+	return false
 }
@@ -0,0 +1,166 @@
+// Package typed is the type-safe counterpart of service,
+// untyped services are adapted into it and back while resources migrate
+package typed
+
+import (
+	"context"
+
+	typedRepo "github.com/di-collective/ditebak/backend/pkg/repo/typed"
+	"github.com/di-collective/ditebak/backend/pkg/service"
+	"github.com/di-collective/ditebak/backend/pkg/service/basic"
+)
+
+// Service of T, P is the payload of update, usually T itself or a partial dto of it
+type Service[T, P any] interface {
+	Reader[T]
+	Writer[T, P]
+}
+
+// Reader of T
+type Reader[T any] interface {
+	// Get one
+	Get(ctx context.Context, id string) (T, error)
+
+	// Find multiple
+	Find(ctx context.Context, page, size int, opt map[string]interface{}) (total int64, rows []T, err error)
+}
+
+// Writer of T, updated by P
+type Writer[T, P any] interface {
+	// Create a new object
+	Create(ctx context.Context, obj T) (T, error)
+
+	// Update an existing object
+	Update(ctx context.Context, id string, obj P) (P, error)
+
+	// Delete an existing object virtually
+	Delete(ctx context.Context, id string) error
+
+	// Remove an existing object physically
+	Remove(ctx context.Context, id string) error
+}
+
+// Basic service of a typed repository, behaves as basic.Service
+func Basic[T, P any](rps typedRepo.Repository[T, P]) Service[T, P] {
+	return Of[T, P](basic.New(typedRepo.Untyped(rps)))
+}
+
+// Of untyped service, object of another type is an error instead of a panic
+func Of[T, P any](svc service.Service) Service[T, P] {
+	return &typedService[T, P]{svc: svc}
+}
+
+// Untyped service of a typed one, payload of another type is an error instead of a panic
+func Untyped[T, P any](svc Service[T, P]) service.Service {
+	return &untypedService[T, P]{svc: svc}
+}
+
+// typedService adapts untyped service
+type typedService[T, P any] struct {
+	svc service.Service
+}
+
+func (s *typedService[T, P]) Get(ctx context.Context, id string) (T, error) {
+	v, err := s.svc.Get(ctx, id)
+	if err != nil {
+		t, _ := v.(T)
+		return t, err
+	}
+
+	return typedRepo.Cast[T](v)
+}
+
+func (s *typedService[T, P]) Find(ctx context.Context, page, size int, opt map[string]interface{}) (int64, []T, error) {
+	total, rows, err := s.svc.Find(ctx, page, size, opt)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	result := make([]T, 0, len(rows))
+	for _, row := range rows {
+		t, err := typedRepo.Cast[T](row)
+		if err != nil {
+			return 0, nil, err
+		}
+		result = append(result, t)
+	}
+
+	return total, result, nil
+}
+
+func (s *typedService[T, P]) Create(ctx context.Context, obj T) (T, error) {
+	v, err := s.svc.Create(ctx, obj)
+	if err != nil {
+		var t T
+		return t, err
+	}
+
+	return typedRepo.Cast[T](v)
+}
+
+func (s *typedService[T, P]) Update(ctx context.Context, id string, obj P) (P, error) {
+	v, err := s.svc.Update(ctx, id, obj)
+	if err != nil {
+		var p P
+		return p, err
+	}
+
+	return typedRepo.Cast[P](v)
+}
+
+func (s *typedService[T, P]) Delete(ctx context.Context, id string) error {
+	return s.svc.Delete(ctx, id)
+}
+
+func (s *typedService[T, P]) Remove(ctx context.Context, id string) error {
+	return s.svc.Remove(ctx, id)
+}
+
+// untypedService adapts typed service
+type untypedService[T, P any] struct {
+	svc Service[T, P]
+}
+
+func (s *untypedService[T, P]) Get(ctx context.Context, id string) (interface{}, error) {
+	return s.svc.Get(ctx, id)
+}
+
+func (s *untypedService[T, P]) Find(ctx context.Context, page, size int, opt map[string]interface{}) (int64, []interface{}, error) {
+	total, rows, err := s.svc.Find(ctx, page, size, opt)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	result := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, row)
+	}
+
+	return total, result, nil
+}
+
+func (s *untypedService[T, P]) Create(ctx context.Context, obj interface{}) (interface{}, error) {
+	t, err := typedRepo.Cast[T](obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.svc.Create(ctx, t)
+}
+
+func (s *untypedService[T, P]) Update(ctx context.Context, id string, obj interface{}) (interface{}, error) {
+	p, err := typedRepo.Cast[P](obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.svc.Update(ctx, id, p)
+}
+
+func (s *untypedService[T, P]) Delete(ctx context.Context, id string) error {
+	return s.svc.Delete(ctx, id)
+}
+
+func (s *untypedService[T, P]) Remove(ctx context.Context, id string) error {
+	return s.svc.Remove(ctx, id)
+}